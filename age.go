@@ -0,0 +1,68 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+// ageIdentities 按 SOPS 的约定读取 age 身份：环境变量 SOPS_AGE_KEY 中的身份、
+// SOPS_AGE_KEY_FILE 指向的文件以及用户配置目录下的 sops/age/keys.txt，默认文件不存在时忽略
+func ageIdentities() ([]age.Identity, error) {
+	var ids []age.Identity
+	add := func(text, from string) error {
+		parsed, err := parseAgeIdentities(text)
+		if err != nil {
+			return fmt.Errorf("%s: %w", from, err)
+		}
+		ids = append(ids, parsed...)
+		return nil
+	}
+	if key := os.Getenv("SOPS_AGE_KEY"); key != "" {
+		if err := add(key, "SOPS_AGE_KEY"); err != nil {
+			return nil, err
+		}
+	}
+	if file := os.Getenv("SOPS_AGE_KEY_FILE"); file != "" {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if err := add(string(b), file); err != nil {
+			return nil, err
+		}
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		file := filepath.Join(dir, "sops", "age", "keys.txt")
+		if b, err := os.ReadFile(file); err == nil {
+			if err := add(string(b), file); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return ids, nil
+}
+
+// parseAgeIdentities 解析 age-keygen 生成的身份文件，忽略空行和 # 开头的注释
+func parseAgeIdentities(text string) ([]age.Identity, error) {
+	return age.ParseIdentities(strings.NewReader(text))
+}
+
+// ageDecrypt 使用 ids 中的任一身份解密 age 文件，文件可以是 ASCII armor 格式
+func ageDecrypt(file []byte, ids []age.Identity) ([]byte, error) {
+	var in io.Reader = bytes.NewReader(file)
+	if bytes.HasPrefix(bytes.TrimSpace(file), []byte(armor.Header)) {
+		in = armor.NewReader(bytes.NewReader(bytes.TrimSpace(file)))
+	}
+	r, err := age.Decrypt(in, ids...)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
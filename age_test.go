@@ -0,0 +1,80 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+)
+
+// testIdentities 读取 testdata/sops/age.key，hello.age 由 age -a 加密给该身份
+func testIdentities(t *testing.T) []age.Identity {
+	t.Helper()
+	ids, err := parseAgeIdentities(readFixture(t, "age.key"))
+	if err != nil || len(ids) != 1 {
+		t.Fatalf("parseAgeIdentities failed: %v", err)
+	}
+	return ids
+}
+
+func TestParseAgeIdentities(t *testing.T) {
+	ids := testIdentities(t)
+	// 身份对应的公钥与 age-keygen 输出的接收者一致
+	if got := ids[0].(*age.X25519Identity).Recipient().String(); got != "age1sd3xyqs83az9w9972rjnxzghhxqfhk4aydey87nqvjwmha40fcnq9cr29y" {
+		t.Errorf("Unexpected recipient %s", got)
+	}
+
+	for _, bad := range []string{
+		"AGE-SECRET-KEY-10QZGQTGR4TUX4MZZ84VPLVYFEMA4L87GVG7XE52GNYCLEQ78ZF9SJEMTG4", // 校验和错误
+		"AGE-PLUGIN-YUBIKEY-1QQQQQQ",
+	} {
+		if _, err := parseAgeIdentities(bad); err == nil {
+			t.Errorf("Expected error for %s", bad)
+		}
+	}
+}
+
+func TestAgeDecrypt(t *testing.T) {
+	ids := testIdentities(t)
+	armored := []byte(readFixture(t, "hello.age"))
+	got, err := ageDecrypt(armored, ids)
+	if err != nil || string(got) != "hello sops\n" {
+		t.Fatalf("Expected hello sops, got %q (%v)", got, err)
+	}
+
+	other, _ := parseAgeIdentities("AGE-SECRET-KEY-1E7C0A2MXEV3DHM03XNQY9MS4LY8W4H0NTD40FJDWAUKFR796ZYGQ39TYRU")
+	if _, err := ageDecrypt(armored, other); err == nil || !strings.Contains(err.Error(), "no identity matched") {
+		t.Errorf("Expected no identity error, got %v", err)
+	}
+
+	// 修改文件头后 MAC 校验失败
+	lines := strings.Split(strings.TrimSpace(string(armored)), "\n")
+	binary, _ := base64.StdEncoding.DecodeString(strings.Join(lines[1:len(lines)-1], ""))
+	tampered := bytes.Replace(binary, []byte("\n---"), []byte("\n-> extra\n\n---"), 1)
+	if _, err := ageDecrypt(tampered, ids); err == nil || !strings.Contains(err.Error(), "header MAC") {
+		t.Errorf("Expected header MAC error, got %v", err)
+	}
+	tampered = append(append([]byte(nil), binary...), 0)
+	if _, err := ageDecrypt(tampered, ids); err == nil {
+		t.Error("Expected payload error")
+	}
+}
+
+func TestAgeIdentitiesFromEnv(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("SOPS_AGE_KEY_FILE", "")
+	t.Setenv("SOPS_AGE_KEY", "AGE-SECRET-KEY-1E7C0A2MXEV3DHM03XNQY9MS4LY8W4H0NTD40FJDWAUKFR796ZYGQ39TYRU")
+	keys := filepath.Join(dir, "sops", "age", "keys.txt")
+	os.MkdirAll(filepath.Dir(keys), 0o700)
+	os.WriteFile(keys, []byte(readFixture(t, "age.key")), 0o600)
+
+	ids, err := ageIdentities()
+	if err != nil || len(ids) != 2 {
+		t.Fatalf("Expected identities from SOPS_AGE_KEY and keys.txt, got %d (%v)", len(ids), err)
+	}
+}
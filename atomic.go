@@ -0,0 +1,59 @@
+package config
+
+import (
+	"reflect"
+	"sync/atomic"
+)
+
+// Atomic 是一个写时复制的 section 句柄
+// 每次刷新都会解码出一个全新的值并原子替换，读者通过 Get 总是拿到完整、一致的快照
+type Atomic[T any] struct {
+	v    atomic.Pointer[T]
+	name string
+}
+
+// Get 返回当前配置快照，永远不为 nil
+// 返回的值在多个 goroutine 间共享，调用方不能修改它
+func (a *Atomic[T]) Get() *T {
+	return a.v.Load()
+}
+
+func (a *Atomic[T]) SectionName() string {
+	return a.name
+}
+
+func (a *Atomic[T]) valueType() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+func (a *Atomic[T]) prepare(data interface{}) (interface{}, error) {
+	fresh := new(T)
+	if err := decodeSection(data, fresh); err != nil {
+		return nil, err
+	}
+	return fresh, validateValue(fresh)
+}
+
+func (a *Atomic[T]) commit(v interface{}) {
+	a.v.Store(v.(*T))
+}
+
+// RegisterAtomic 使用泛型注册一个原子 section，section 名称从类型名推断
+// 与 Register 不同，UpdateConfig 不会原地修改旧值，而是原子替换为新值，读者无需加锁
+// 用法: var Server = config.RegisterAtomic[server]()
+//
+//	port := Server.Get().Port
+func RegisterAtomic[T any]() *Atomic[T] {
+	return RegisterAtomicTo[T](std)
+}
+
+// RegisterAtomicTo 与 RegisterAtomic 相同，但注册到指定的 Loader
+func RegisterAtomicTo[T any](l *Loader) *Atomic[T] {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	a := &Atomic[T]{name: lcFirst(t.Name())}
+	a.v.Store(new(T))
+	// 注册时的刷新会用默认值和配置替换这个零值
+
+	l.register(a)
+	return a
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestRegisterAtomic(t *testing.T) {
+	l := newTestLoader(t)
+	srv := RegisterAtomicTo[server](l)
+
+	first := srv.Get()
+	if first.Port != 8080 || first.Name != "app" {
+		t.Fatalf("Unexpected initial value: %+v", first)
+	}
+
+	if err := l.UpdateConfig([]byte("server:\n  port: 9090\n"), "merge"); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+
+	second := srv.Get()
+	if second == first {
+		t.Fatal("Expected a fresh snapshot after update")
+	}
+	if second.Port != 9090 || second.Name != "app" {
+		t.Errorf("Unexpected updated value: %+v", second)
+	}
+	// 旧快照保持不变
+	if first.Port != 8080 {
+		t.Errorf("Expected old snapshot to be untouched, got %+v", first)
+	}
+}
+
+func TestRegisterAtomicMissingSection(t *testing.T) {
+	l := newTestLoader(t)
+	gw := RegisterAtomicTo[gateway](l)
+	if gw.Get() == nil {
+		t.Fatal("Expected Get to never return nil")
+	}
+	if gw.SectionName() != "gateway" {
+		t.Errorf("Expected section name 'gateway', got '%s'", gw.SectionName())
+	}
+}
+
+// TestRegisterAtomicConcurrent 在 -race 下验证读者与更新之间没有数据竞争
+func TestRegisterAtomicConcurrent(t *testing.T) {
+	l := newTestLoader(t)
+	srv := RegisterAtomicTo[server](l)
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				s := srv.Get()
+				if s.Name != "app" {
+					t.Errorf("Expected consistent snapshot, got %+v", s)
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 50; i++ {
+		data := []byte("server:\n  port: 9090\n")
+		if i%2 == 1 {
+			data = []byte("server:\n  port: 8080\n")
+		}
+		if err := l.UpdateConfig(data, "merge"); err != nil {
+			t.Fatalf("UpdateConfig failed: %v", err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
@@ -0,0 +1,234 @@
+// Package consul 通过 Consul KV HTTP API 读取配置，使用 blocking query 监听变化，
+// Client 是优先级为 config.PriorityRemote 的配置来源
+//
+// 配置可以是单个 key 中保存的整个 YAML，也可以是某个前缀下的 key 树：
+// 前缀为 app/ 时，app/server/port 映射为 server.port，每个值按 YAML 标量解析
+//
+// 用法:
+//
+//	if err := consul.Start(ctx); err != nil {
+//		log.Printf("consul: %v", err)
+//	}
+package consul
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/teatak/config/v2"
+	"github.com/teatak/config/v2/internal/remote"
+	"github.com/teatak/config/v2/sections"
+)
+
+// DefaultWait 是 blocking query 默认的最长等待时间
+const DefaultWait = 5 * time.Minute
+
+// Config 描述要读取的 Consul KV 配置
+type Config struct {
+	Address    string        // 例如 http://127.0.0.1:8500，不带协议时使用 http
+	Token      string        // ACL token，通过 X-Consul-Token 发送
+	Datacenter string        // 数据中心，空表示 agent 所在的数据中心
+	Key        string        // 保存整个 YAML 配置的 key，与 Prefix 二选一
+	Prefix     string        // key 前缀，前缀下的每个 key 对应一个配置项，不以 / 结尾时自动补上
+	Wait       time.Duration // blocking query 的最长等待时间，默认 5m
+	Mode       string        // merge（默认）或 overwrite，同 nacos.Config
+	HTTPClient *http.Client  // 默认使用 http.DefaultClient
+}
+
+// FromSection 根据 sections.Consul 生成 Config
+func FromSection() Config {
+	s := sections.Consul
+	return Config{
+		Address:    s.Address,
+		Token:      s.Token.Value(),
+		Datacenter: s.Datacenter,
+		Key:        s.Key,
+		Prefix:     s.Prefix,
+		Wait:       s.Wait,
+		Mode:       s.Mode,
+	}
+}
+
+// Client 通过 Consul KV HTTP API 读取和监听配置，实现 config.Source、config.Watcher 和 config.Overwriter
+type Client struct {
+	cfg Config
+
+	mu    sync.Mutex
+	index uint64   // 最近一次响应的 X-Consul-Index，blocking query 等待它之后的变化
+	hash  [32]byte // 最近一次读取的内容的 hash，index 变化但内容不变时不重复推送
+}
+
+// NewClient 创建一个 Client，未设置的字段使用默认值
+func NewClient(cfg Config) *Client {
+	if !strings.Contains(cfg.Address, "://") {
+		cfg.Address = "http://" + cfg.Address
+	}
+	cfg.Address = strings.TrimRight(cfg.Address, "/")
+	cfg.Prefix = remote.Prefix(strings.TrimLeft(cfg.Prefix, "/"))
+	if cfg.Wait <= 0 {
+		cfg.Wait = DefaultWait
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	return &Client{cfg: cfg}
+}
+
+// Start 在 sections.Consul.Enable 为 true 时以 source 返回的名称调用默认 Loader 的 AddSource 和 WatchSource，与 nacos.Start 相同
+func Start(ctx context.Context) error {
+	if !sections.Consul.Enable {
+		return nil
+	}
+	c := NewClient(FromSection())
+	return remote.Start(ctx, config.Default(), c.source(), c)
+}
+
+// source 返回带有 key 或前缀的来源名称，也用于错误信息，例如 "consul:app/"
+func (c *Client) source() string {
+	if c.cfg.Key != "" {
+		return "consul:" + c.cfg.Key
+	}
+	return "consul:" + c.cfg.Prefix
+}
+
+// Overwrite 在 Mode 为 overwrite 时返回 true
+func (c *Client) Overwrite() bool {
+	return c.cfg.Mode == "overwrite"
+}
+
+// Load 读取配置并解析为配置树
+// 使用 Key 时按 yaml 或 JSON 解析该 key 的值；使用 Prefix 时把前缀下的 key 树转换为配置树
+func (c *Client) Load(ctx context.Context) (map[string]any, error) {
+	data, _, err := c.read(ctx, false)
+	return data, err
+}
+
+// Watch 使用 blocking query 持续等待变化，内容变化时推送最新的内容，直到 ctx 结束
+func (c *Client) Watch(ctx context.Context) <-chan config.Event {
+	return remote.Watch(ctx, func(ctx context.Context) (map[string]any, error) {
+		data, changed, err := c.read(ctx, true)
+		if err != nil || !changed {
+			return nil, err
+		}
+		return data, nil
+	})
+}
+
+// read 读取一次配置并解析，同时返回内容是否与上次读取的不同，block 的含义同 fetch
+func (c *Client) read(ctx context.Context, block bool) (map[string]any, bool, error) {
+	pairs, err := c.fetch(ctx, block)
+	if err != nil {
+		return nil, false, err
+	}
+	h := sha256.New()
+	for _, kv := range pairs {
+		h.Write([]byte(kv.Key))
+		h.Write([]byte{0})
+		h.Write(kv.Value)
+		h.Write([]byte{0})
+	}
+	var sum [32]byte
+	h.Sum(sum[:0])
+	c.mu.Lock()
+	changed := sum != c.hash
+	c.hash = sum
+	c.mu.Unlock()
+
+	var data map[string]any
+	if c.cfg.Key != "" {
+		data, err = config.Parse(pairs[0].Value)
+	} else {
+		kvs := make([]remote.KV, len(pairs))
+		for i, kv := range pairs {
+			kvs[i] = remote.KV{Key: kv.Key, Value: kv.Value}
+		}
+		data, err = remote.Tree(c.cfg.Prefix, kvs)
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("get %s: %w", c.source(), err)
+	}
+	return data, changed, nil
+}
+
+// kvPair 是 /v1/kv 响应中的一项，Value 为 base64，目录项为 null
+type kvPair struct {
+	Key   string
+	Value []byte
+}
+
+// fetch 读取 key 或前缀下的所有 key，block 为 true 时以上次的 X-Consul-Index 发起 blocking query
+// 使用 Key 时返回的 key 不为空，不存在时返回错误
+func (c *Client) fetch(ctx context.Context, block bool) ([]kvPair, error) {
+	q := url.Values{}
+	if c.cfg.Datacenter != "" {
+		q.Set("dc", c.cfg.Datacenter)
+	}
+	key := c.cfg.Key
+	if key == "" {
+		key = c.cfg.Prefix
+		q.Set("recurse", "true")
+	}
+	c.mu.Lock()
+	index := c.index
+	c.mu.Unlock()
+	if block && index > 0 {
+		q.Set("index", strconv.FormatUint(index, 10))
+		q.Set("wait", fmt.Sprintf("%ds", int(c.cfg.Wait.Seconds())))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.cfg.Address+"/v1/kv/"+strings.TrimLeft(key, "/")+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.cfg.Token != "" {
+		req.Header.Set("X-Consul-Token", c.cfg.Token)
+	}
+	resp, err := c.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("get %s: %w", c.source(), err)
+	}
+	defer resp.Body.Close()
+
+	// index 变小说明 Consul 的状态被重置，需要从头开始
+	if next, err := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64); err == nil {
+		if next < index {
+			next = 0
+		}
+		c.mu.Lock()
+		c.index = next
+		c.mu.Unlock()
+	}
+
+	var pairs []kvPair
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if err := json.NewDecoder(resp.Body).Decode(&pairs); err != nil {
+			return nil, fmt.Errorf("get %s: %w", c.source(), err)
+		}
+	case http.StatusNotFound:
+		// 前缀下没有 key 时视为空配置
+		if c.cfg.Key != "" {
+			return nil, fmt.Errorf("get %s: %w", c.source(), errNotFound)
+		}
+	default:
+		b, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("get %s: unexpected status %s: %s", c.source(), resp.Status, strings.TrimSpace(string(b)))
+	}
+
+	if c.cfg.Key != "" && len(pairs) == 0 {
+		return nil, fmt.Errorf("get %s: %w", c.source(), errNotFound)
+	}
+	return pairs, nil
+}
+
+var errNotFound = errors.New("key not found")
@@ -0,0 +1,179 @@
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/teatak/config/v2"
+)
+
+// fakeConsul 实现 Consul KV HTTP API 中读取和 blocking query 的部分
+type fakeConsul struct {
+	mu      sync.Mutex
+	kvs     map[string]string
+	index   uint64
+	changed chan struct{} // 每次写入时关闭并替换
+}
+
+func newFakeConsul(kvs map[string]string) *fakeConsul {
+	return &fakeConsul{kvs: kvs, index: 10, changed: make(chan struct{})}
+}
+
+func (f *fakeConsul) put(key, value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.kvs[key] = value
+	f.index++
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+func (f *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Consul-Token") != "acl-token" {
+		http.Error(w, "ACL not found", http.StatusForbidden)
+		return
+	}
+	key, ok := strings.CutPrefix(r.URL.Path, "/v1/kv/")
+	if !ok || r.URL.Query().Get("dc") != "dc1" {
+		http.NotFound(w, r)
+		return
+	}
+	q := r.URL.Query()
+	if index, _ := strconv.ParseUint(q.Get("index"), 10, 64); index > 0 {
+		wait, err := time.ParseDuration(q.Get("wait"))
+		if err != nil {
+			http.Error(w, "invalid wait", http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		current, changed := f.index, f.changed
+		f.mu.Unlock()
+		if index >= current {
+			select {
+			case <-changed:
+			case <-time.After(wait):
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(f.index, 10))
+	var keys []string
+	for k := range f.kvs {
+		if k == key || (q.Has("recurse") && strings.HasPrefix(k, key)) {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	sort.Strings(keys)
+	pairs := make([]map[string]interface{}, len(keys))
+	for i, k := range keys {
+		var v interface{}
+		if !strings.HasSuffix(k, "/") {
+			v = []byte(f.kvs[k])
+		}
+		pairs[i] = map[string]interface{}{"Key": k, "Value": v, "ModifyIndex": f.index}
+	}
+	json.NewEncoder(w).Encode(pairs)
+}
+
+type server struct {
+	Name string `yaml:"name"`
+	Port int    `yaml:"port"`
+}
+
+func TestPrefix(t *testing.T) {
+	fake := newFakeConsul(map[string]string{
+		"app/":             "",
+		"app/server/port":  "9090",
+		"apple/server/url": "ignored",
+		"other/server/url": "ignored",
+	})
+	ts := httptest.NewServer(fake)
+	defer ts.Close()
+
+	c := NewClient(Config{Address: ts.URL, Token: "acl-token", Datacenter: "dc1", Prefix: "app", Wait: time.Second})
+	path := filepath.Join(t.TempDir(), "app.yaml")
+	if err := os.WriteFile(path, []byte("server:\n  name: local\n  port: 8080\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	l := config.New(config.WithConfigPath(path), config.WithSource(c.source(), config.PriorityRemote, c))
+	srv := config.RegisterTo(l, &server{})
+	if srv.Port != 9090 || srv.Name != "local" {
+		t.Errorf("Expected merged config, got %+v", srv)
+	}
+	if got := l.Dump(); len(got) != 1 {
+		t.Errorf("Expected keys outside the prefix to be ignored, got %v", got)
+	}
+	changes, cancelWatch := l.Watch("server")
+	defer cancelWatch()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	l.WatchSources(ctx)
+
+	fake.put("app/server/name", "remote")
+	select {
+	case <-changes:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Timed out waiting for consul change")
+	}
+	if srv.Name != "remote" || srv.Port != 9090 {
+		t.Errorf("Expected name from consul, got %+v", srv)
+	}
+	if got := l.Explain("server.name"); got[len(got)-1].Source != "consul:app/" {
+		t.Errorf("Expected name from consul:app/, got %v", got)
+	}
+
+	// index 变化但内容不变时不重复推送
+	versions := len(l.History())
+	fake.put("other/server/url", "still ignored")
+	time.Sleep(100 * time.Millisecond)
+	if got := len(l.History()); got != versions {
+		t.Errorf("Expected no new revision for unrelated change, got %d -> %d", versions, got)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	ts := httptest.NewServer(newFakeConsul(map[string]string{"config/app.yaml": "server:\n  port: 9090\n"}))
+	defer ts.Close()
+
+	c := NewClient(Config{Address: ts.URL, Token: "acl-token", Datacenter: "dc1", Key: "config/app.yaml"})
+	data, err := c.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if port := data["server"].(map[string]interface{})["port"]; port != 9090 {
+		t.Errorf("Expected port 9090, got %v", data)
+	}
+
+	c = NewClient(Config{Address: ts.URL, Datacenter: "dc1", Key: "config/app.yaml"})
+	if _, err := c.Load(context.Background()); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Expected ACL error, got %v", err)
+	}
+	c = NewClient(Config{Address: ts.URL, Token: "acl-token", Datacenter: "dc1", Key: "config/missing.yaml"})
+	if _, err := c.Load(context.Background()); !errors.Is(err, errNotFound) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+	// 前缀下没有 key 时视为空配置
+	c = NewClient(Config{Address: strings.TrimPrefix(ts.URL, "http://"), Token: "acl-token", Datacenter: "dc1", Prefix: "empty/"})
+	if data, err := c.Load(context.Background()); err != nil || len(data) != 0 {
+		t.Errorf("Expected empty config, got %v, %v", data, err)
+	}
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// applyDefaults 根据 default 标签为结构体字段设置默认值，v 必须是指针或可寻址的值
+//
+//	Port    int           `yaml:"port" default:"8080"`
+//	Timeout time.Duration `yaml:"timeout" default:"5s"`
+//	Addrs   []string      `yaml:"addrs" default:"localhost:6379,localhost:6380"`
+//	Labels  map[string]string `yaml:"labels" default:"{env: dev}"`
+//
+// 字符串按字面量设置；切片可以用逗号分隔，也可以写成 yaml 数组；其余类型按 yaml 标量解析
+// 没有 default 标签的嵌套结构体会递归处理；值为 nil 的结构体指针字段在 data 中出现、
+// 或者指向的结构体带有 default 标签时先分配再递归，否则保持 nil
+// data 是之后要解码到 v 中的配置子树：yaml 会把配置的 map 合并到已有的 map 中，
+// 因此 map 字段在 data 中出现时不设置默认值，配置的 map 整体替换默认值
+func applyDefaults(v reflect.Value, data interface{}) error {
+	return applyDefaultsIn(v, data, map[reflect.Type]bool{})
+}
+
+// applyDefaultsIn 是 applyDefaults 的递归实现，active 是正在处理的外层结构体类型
+// 没有配置的自引用指针字段（例如链表的 next）不会分配，避免无限递归
+func applyDefaultsIn(v reflect.Value, data interface{}, active map[reflect.Type]bool) error {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	m, _ := toStringMap(data)
+	t := v.Type()
+	active[t] = true
+	defer delete(active, t)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		fv := v.Field(i)
+		sub, configured := m[yamlFieldName(f)]
+		if _, opts, _ := strings.Cut(f.Tag.Get("yaml"), ","); strings.Contains(opts, "inline") {
+			sub, configured = data, false
+		}
+		tag, ok := f.Tag.Lookup("default")
+		if !ok {
+			if et := fv.Type(); et.Kind() == reflect.Ptr && fv.IsNil() && et.Elem().Kind() == reflect.Struct &&
+				(configured || !active[et.Elem()] && hasDefaults(et.Elem(), map[reflect.Type]bool{})) {
+				fv.Set(reflect.New(et.Elem()))
+			}
+			if err := applyDefaultsIn(fv.Addr(), sub, active); err != nil {
+				return err
+			}
+			continue
+		}
+		if configured && fv.Kind() == reflect.Map {
+			continue
+		}
+		if err := setDefault(fv, tag); err != nil {
+			return fmt.Errorf("default value %q for field %s.%s: %w", tag, t.Name(), f.Name, err)
+		}
+	}
+	return nil
+}
+
+// hasDefaults 判断结构体类型 t 或其嵌套的结构体（包括结构体指针）中是否有带 default 标签的字段
+// seen 记录已经检查过的类型，避免自引用的类型无限递归
+func hasDefaults(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		if _, ok := f.Tag.Lookup("default"); ok {
+			return true
+		}
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct && hasDefaults(ft, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// setDefault 将 default 标签的值设置到字段上
+func setDefault(fv reflect.Value, tag string) error {
+	switch {
+	case fv.Kind() == reflect.String:
+		fv.SetString(tag)
+		return nil
+	case fv.Kind() == reflect.Slice && !strings.HasPrefix(strings.TrimSpace(tag), "["):
+		parts := strings.Split(tag, ",")
+		s := reflect.MakeSlice(fv.Type(), len(parts), len(parts))
+		for i, p := range parts {
+			if err := setDefault(s.Index(i), strings.TrimSpace(p)); err != nil {
+				return err
+			}
+		}
+		fv.Set(s)
+		return nil
+	}
+	return yaml.Unmarshal([]byte(tag), fv.Addr().Interface())
+}
+
+// decodeSection 将配置子树解码到全新的 out 中：先设置默认值，再应用 yaml
+// data 为 nil（section 不存在或被删除）时 out 只包含默认值
+func decodeSection(data interface{}, out interface{}) error {
+	if err := applyDefaults(reflect.ValueOf(out), data); err != nil {
+		return err
+	}
+	return decodeInto(data, out)
+}
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type defaultsEndpoint struct {
+	Host string `yaml:"host" default:"localhost"`
+	Port int    `yaml:"port" default:"6379"`
+}
+
+type defaultsServer struct {
+	Name     string            `yaml:"name" default:"app"`
+	Port     int               `yaml:"port" default:"8080"`
+	Debug    bool              `yaml:"debug" default:"true"`
+	Ratio    float64           `yaml:"ratio" default:"0.5"`
+	Timeout  time.Duration     `yaml:"timeout" default:"5s"`
+	Origins  []string          `yaml:"origins" default:"a.com, b.com"`
+	Ports    []int             `yaml:"ports" default:"[80, 443]"`
+	Labels   map[string]string `yaml:"labels" default:"{env: dev}"`
+	Endpoint defaultsEndpoint  `yaml:"endpoint"`
+	Optional *defaultsEndpoint `yaml:"optional"`
+	NoTag    string            `yaml:"noTag"`
+}
+
+func TestApplyDefaults(t *testing.T) {
+	var s defaultsServer
+	if err := applyDefaults(reflect.ValueOf(&s), nil); err != nil {
+		t.Fatalf("applyDefaults failed: %v", err)
+	}
+	want := defaultsServer{
+		Name:     "app",
+		Port:     8080,
+		Debug:    true,
+		Ratio:    0.5,
+		Timeout:  5 * time.Second,
+		Origins:  []string{"a.com", "b.com"},
+		Ports:    []int{80, 443},
+		Labels:   map[string]string{"env": "dev"},
+		Endpoint: defaultsEndpoint{Host: "localhost", Port: 6379},
+		Optional: &defaultsEndpoint{Host: "localhost", Port: 6379},
+	}
+	if !reflect.DeepEqual(s, want) {
+		t.Errorf("applyDefaults mismatch\ngot:  %+v\nwant: %+v", s, want)
+	}
+}
+
+// defaultsNode 是自引用的结构体，没有配置的 next 不能无限分配下去
+type defaultsNode struct {
+	Name  string        `yaml:"name" default:"node"`
+	Next  *defaultsNode `yaml:"next"`
+	Plain *struct {
+		Value string `yaml:"value"`
+	} `yaml:"plain"`
+}
+
+func TestApplyDefaultsPointers(t *testing.T) {
+	var n defaultsNode
+	if err := applyDefaults(reflect.ValueOf(&n), map[string]interface{}{"next": map[string]interface{}{}}); err != nil {
+		t.Fatalf("applyDefaults failed: %v", err)
+	}
+	if n.Name != "node" || n.Next == nil || n.Next.Name != "node" || n.Next.Next != nil {
+		t.Errorf("Expected configured next to get defaults, got %+v", n)
+	}
+	// 没有 default 标签的结构体指针只在配置中出现时分配
+	if n.Plain != nil {
+		t.Errorf("Expected pointer without defaults to stay nil, got %+v", n.Plain)
+	}
+}
+
+func TestApplyDefaultsInvalidTag(t *testing.T) {
+	var s struct {
+		Port int `default:"not-a-number"`
+	}
+	if err := applyDefaults(reflect.ValueOf(&s), nil); err == nil {
+		t.Error("Expected error for invalid default tag")
+	}
+}
+
+type defaultsServerSection struct {
+	Port int `yaml:"port" default:"8080"`
+}
+
+func (s *defaultsServerSection) SectionName() string { return "server" }
+
+func TestDefaultsOnReload(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml": "config: dev\n",
+		"dev.yaml": `
+defaultsServer:
+  port: 9090
+  labels:
+    team: core
+  endpoint:
+    host: redis
+  optional:
+    port: 7000
+redis:
+  default:
+    password: admin
+server:
+  port: 7070
+`,
+	})
+	l := New(WithConfigPath(filepath.Join(dir, "app.yaml")))
+
+	srv := RegisterTo(l, &defaultsServer{})
+	eps := RegisterMapTo[*defaultsEndpoint](l, "redis")
+	atomicSrv := RegisterAtomicTo[defaultsServer](l)
+	loaded := &defaultsServerSection{}
+	l.Load(loaded)
+
+	if srv.Port != 9090 || srv.Name != "app" || srv.Timeout != 5*time.Second {
+		t.Errorf("Expected yaml over defaults, got %+v", srv)
+	}
+	if srv.Endpoint.Host != "redis" || srv.Endpoint.Port != 6379 {
+		t.Errorf("Expected nested defaults, got %+v", srv.Endpoint)
+	}
+	if o := srv.Optional; o == nil || o.Host != "localhost" || o.Port != 7000 {
+		t.Errorf("Expected defaults for pointer field, got %+v", o)
+	}
+	// 配置的 map 整体替换默认值，不与默认值合并
+	if !reflect.DeepEqual(srv.Labels, map[string]string{"team": "core"}) {
+		t.Errorf("Expected configured labels to replace the default, got %v", srv.Labels)
+	}
+	if d := eps.Default(); d == nil || d.Host != "localhost" || d.Port != 6379 {
+		t.Errorf("Expected defaults for map entries, got %+v", d)
+	}
+	if a := atomicSrv.Get(); a.Port != 9090 || a.Name != "app" {
+		t.Errorf("Expected defaults for atomic section, got %+v", a)
+	}
+	if loaded.Port != 7070 {
+		t.Errorf("Expected Load section port 7070, got %d", loaded.Port)
+	}
+
+	// overwrite 删除的 key 恢复为默认值，而不是保留旧值
+	err := l.UpdateConfig([]byte(`
+defaultsServer:
+  name: updated
+redis:
+  default:
+    host: other
+`), "overwrite")
+	if err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	if srv.Port != 8080 || srv.Name != "updated" || srv.Endpoint.Host != "localhost" || srv.Labels["env"] != "dev" || srv.Optional.Port != 6379 {
+		t.Errorf("Expected removed keys to revert to defaults, got %+v", srv)
+	}
+	if a := atomicSrv.Get(); a.Port != 8080 {
+		t.Errorf("Expected atomic section to revert to default port, got %d", a.Port)
+	}
+	if d := eps.Default(); d == nil || d.Host != "other" || d.Port != 6379 {
+		t.Errorf("Expected map entry to be rebuilt with defaults, got %+v", d)
+	}
+	// section 整体被删除时也恢复为默认值
+	if loaded.Port != 8080 {
+		t.Errorf("Expected Load section to revert to default, got %d", loaded.Port)
+	}
+}
@@ -0,0 +1,180 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ChangeKind 表示配置项的变化类型
+type ChangeKind string
+
+const (
+	Added    ChangeKind = "added"
+	Removed  ChangeKind = "removed"
+	Modified ChangeKind = "modified"
+)
+
+// Redacted 是敏感配置项在 Diff 结果中的替代值
+const Redacted = "******"
+
+// Change 描述一个配置项的变化
+// 新增或删除整个子树时只报告子树的根路径，Old / New 为整个子树
+type Change struct {
+	Path string      // 以 "." 分隔的 key 路径，例如 redis.default.addrs
+	Kind ChangeKind  // added / removed / modified
+	Old  interface{} // 变化前的值，Added 时为 nil
+	New  interface{} // 变化后的值，Removed 时为 nil
+}
+
+// String 以 "+ path: new"、"- path: old"、"~ path: old -> new" 的形式描述变化
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("+ %s: %v", c.Path, c.New)
+	case Removed:
+		return fmt.Sprintf("- %s: %v", c.Path, c.Old)
+	}
+	return fmt.Sprintf("~ %s: %v -> %v", c.Path, c.Old, c.New)
+}
+
+// Diff 比较两棵原始配置树，返回按路径排序的变化列表
+// 两边都是 map 时递归比较，其余情况（包括数组）整体比较
+// 敏感配置项（见 IsSecretPath）的值会被替换为 Redacted，包括新增或删除的子树中的敏感项
+func Diff(old, new map[string]interface{}) []Change {
+	return redactChanges(diffTrees(old, new), IsSecretPath)
+}
+
+// redactChanges 原地将 changes 中 isSecret 判断为敏感的值替换为 Redacted
+func redactChanges(changes []Change, isSecret func(path []string) bool) []Change {
+	for i := range changes {
+		path := strings.Split(changes[i].Path, ".")
+		changes[i].Old = redactValue(path, changes[i].Old, isSecret)
+		changes[i].New = redactValue(path, changes[i].New, isSecret)
+	}
+	return changes
+}
+
+// diffTrees 与 Diff 相同，但不做脱敏，仅供内部使用
+func diffTrees(old, new map[string]interface{}) []Change {
+	var changes []Change
+	diffNode("", old, new, &changes)
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+func diffNode(path string, old, new interface{}, changes *[]Change) {
+	om, oldIsMap := toStringMap(old)
+	nm, newIsMap := toStringMap(new)
+	if !oldIsMap || !newIsMap {
+		if !reflect.DeepEqual(old, new) {
+			*changes = append(*changes, Change{Path: path, Kind: Modified, Old: old, New: new})
+		}
+		return
+	}
+	for k, nv := range nm {
+		ov, ok := om[k]
+		if !ok {
+			*changes = append(*changes, Change{Path: joinPath(path, k), Kind: Added, New: nv})
+			continue
+		}
+		diffNode(joinPath(path, k), ov, nv, changes)
+	}
+	for k, ov := range om {
+		if _, ok := nm[k]; !ok {
+			*changes = append(*changes, Change{Path: joinPath(path, k), Kind: Removed, Old: ov})
+		}
+	}
+}
+
+var (
+	secretMu sync.RWMutex
+	// secretPaths 是通过 RedactPaths 注册的路径，默认包含名称本身不像凭据的内置凭据，
+	// 例如 wechatpay 的 API 密钥；etcd.key、consul.key 等同名的 KV 名称不脱敏
+	secretPaths = [][]string{{"wechatpay", "*", "key"}}
+)
+
+// RedactPaths 注册额外需要脱敏的配置路径，对所有 Loader 生效，"*" 匹配任意一段，重复注册的路径会被忽略
+// 用法: config.RedactPaths("mysql.*.dsn", "riff.url")
+// section 中 Secret 类型和带 secret:"true" 标签的字段不需要注册，见 Secret
+func RedactPaths(patterns ...string) {
+	secretMu.Lock()
+	defer secretMu.Unlock()
+next:
+	for _, p := range patterns {
+		segs := strings.Split(p, ".")
+		for _, existing := range secretPaths {
+			if slices.Equal(existing, segs) {
+				continue next
+			}
+		}
+		secretPaths = append(secretPaths, segs)
+	}
+}
+
+// IsSecretPath 判断配置路径是否敏感
+// 最后一段 key 的小写形式包含 password、secret、token 或 privatekey 时视为敏感，
+// 例如 auth.jwt_secret、redis.*.password、alipay.*.privateKey；
+// 此外还包括 wechatpay.*.key 和通过 RedactPaths 注册的路径
+func IsSecretPath(path []string) bool {
+	if len(path) == 0 {
+		return false
+	}
+	last := strings.ToLower(path[len(path)-1])
+	for _, word := range []string{"password", "secret", "token", "privatekey"} {
+		if strings.Contains(last, word) {
+			return true
+		}
+	}
+
+	secretMu.RLock()
+	defer secretMu.RUnlock()
+	for _, pattern := range secretPaths {
+		if matchPath(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchPath(pattern, path []string) bool {
+	if len(pattern) != len(path) {
+		return false
+	}
+	for i, seg := range pattern {
+		if seg != "*" && seg != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// redactValue 返回 path 处的值 v 脱敏后的副本，v 为 map 时递归处理其中的敏感项，数组元素使用数组本身的路径
+func redactValue(path []string, v interface{}, isSecret func(path []string) bool) interface{} {
+	if v == nil {
+		return nil
+	}
+	if isSecret(path) {
+		return Redacted
+	}
+	switch n := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(n))
+		for k, vv := range n {
+			out[k] = redactValue(append(path[:len(path):len(path)], k), vv, isSecret)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(n))
+		for i, vv := range n {
+			out[i] = redactValue(path, vv, isSecret)
+		}
+		return out
+	}
+	return v
+}
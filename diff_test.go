@@ -0,0 +1,147 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffTrees(t *testing.T) {
+	old := parseTree(t, `
+server:
+  port: 8080
+  name: app
+redis:
+  default:
+    addrs: [a:6379]
+    db: 2
+  session:
+    db: 1
+log:
+  level: INFO
+`)
+	new := parseTree(t, `
+server:
+  port: 9090
+  name: app
+  url: http://localhost
+redis:
+  default:
+    addrs: [a:6379, b:6379]
+    db: 2
+mongo:
+  default:
+    uri: mongodb://localhost
+log: disabled
+`)
+
+	want := []Change{
+		{Path: "log", Kind: Modified, Old: map[string]interface{}{"level": "INFO"}, New: "disabled"},
+		{Path: "mongo", Kind: Added, New: map[string]interface{}{"default": map[string]interface{}{"uri": "mongodb://localhost"}}},
+		{Path: "redis.default.addrs", Kind: Modified, Old: []interface{}{"a:6379"}, New: []interface{}{"a:6379", "b:6379"}},
+		{Path: "redis.session", Kind: Removed, Old: map[string]interface{}{"db": 1}},
+		{Path: "server.port", Kind: Modified, Old: 8080, New: 9090},
+		{Path: "server.url", Kind: Added, New: "http://localhost"},
+	}
+	if got := diffTrees(old, new); !reflect.DeepEqual(got, want) {
+		t.Errorf("diffTrees mismatch\ngot:  %+v\nwant: %+v", got, want)
+	}
+	if got := diffTrees(old, old); len(got) != 0 {
+		t.Errorf("Expected no changes for identical trees, got %+v", got)
+	}
+}
+
+func TestDiffRedactsSecrets(t *testing.T) {
+	old := parseTree(t, `
+auth:
+  jwt_secret: old-secret
+redis:
+  default:
+    addrs: [a:6379]
+    password: old-pass
+`)
+	new := parseTree(t, `
+auth:
+  jwt_secret: new-secret
+redis:
+  default:
+    addrs: [a:6379]
+    password: new-pass
+  session:
+    db: 1
+    password: session-pass
+`)
+
+	want := []Change{
+		{Path: "auth.jwt_secret", Kind: Modified, Old: Redacted, New: Redacted},
+		{Path: "redis.default.password", Kind: Modified, Old: Redacted, New: Redacted},
+		{Path: "redis.session", Kind: Added, New: map[string]interface{}{"db": 1, "password": Redacted}},
+	}
+	if got := Diff(old, new); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff mismatch\ngot:  %+v\nwant: %+v", got, want)
+	}
+}
+
+func TestRedactPaths(t *testing.T) {
+	secretMu.Lock()
+	saved := secretPaths
+	secretMu.Unlock()
+	defer func() {
+		secretMu.Lock()
+		secretPaths = saved
+		secretMu.Unlock()
+	}()
+
+	if IsSecretPath([]string{"mysql", "main", "dsn"}) {
+		t.Fatal("mysql.main.dsn should not be secret before RedactPaths")
+	}
+	RedactPaths("mysql.*.dsn")
+	if !IsSecretPath([]string{"mysql", "main", "dsn"}) {
+		t.Error("Expected mysql.main.dsn to be secret after RedactPaths")
+	}
+	if IsSecretPath([]string{"mysql", "dsn"}) {
+		t.Error("Wildcard should match exactly one segment")
+	}
+}
+
+func TestIsSecretPathKey(t *testing.T) {
+	// 只有已知的凭据路径中的 key 是敏感的，etcd 和 consul 的 key 是 KV 名称
+	if !IsSecretPath([]string{"wechatpay", "default", "key"}) {
+		t.Error("Expected wechatpay.default.key to be secret")
+	}
+	for _, path := range [][]string{{"etcd", "key"}, {"consul", "key"}} {
+		if IsSecretPath(path) {
+			t.Errorf("Expected %v not to be secret", path)
+		}
+	}
+	if got := Diff(config{"etcd": map[string]interface{}{"key": "/a"}}, config{"etcd": map[string]interface{}{"key": "/b"}}); got[0].New != "/b" {
+		t.Errorf("Expected etcd.key to be shown in Diff, got %+v", got)
+	}
+}
+
+func TestChangeString(t *testing.T) {
+	cases := map[string]Change{
+		"+ server.url: http://localhost": {Path: "server.url", Kind: Added, New: "http://localhost"},
+		"- redis.session: map[db:1]":     {Path: "redis.session", Kind: Removed, Old: map[string]interface{}{"db": 1}},
+		"~ server.port: 8080 -> 9090":    {Path: "server.port", Kind: Modified, Old: 8080, New: 9090},
+	}
+	for want, c := range cases {
+		if got := c.String(); got != want {
+			t.Errorf("Expected %q, got %q", want, got)
+		}
+	}
+}
+
+func TestApplyReportsChanges(t *testing.T) {
+	l := newTestLoader(t)
+	report, err := l.Apply([]byte("server:\n  port: 9090\nauth:\n  jwt_secret: s3cr3t\n"), "merge")
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	want := []Change{
+		{Path: "auth", Kind: Added, New: map[string]interface{}{"jwt_secret": Redacted}},
+		{Path: "server.port", Kind: Modified, Old: 8080, New: 9090},
+	}
+	if !reflect.DeepEqual(report.Changes, want) {
+		t.Errorf("Report changes mismatch\ngot:  %+v\nwant: %+v", report.Changes, want)
+	}
+}
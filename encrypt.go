@@ -0,0 +1,213 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// 加密值的格式与 SOPS 相同：ENC[AES256_GCM,data:<base64>,iv:<base64>,tag:<base64>,type:str]
+// 配置文件中任何整个值都是这种格式的字符串都会在加载时用密钥解密，section 拿到的是明文；
+// 原始配置树、历史版本和 Diff 中保存的依然是密文
+const (
+	encPrefix = "ENC[AES256_GCM,"
+	encSuffix = "]"
+)
+
+// SecretKeySize 是加密配置值使用的 AES-256 密钥长度
+const SecretKeySize = 32
+
+// WithSecretKey 指定解密配置值的密钥，优先于 WithSecretKeyFile 和环境变量
+func WithSecretKey(key []byte) Option {
+	return func(l *Loader) {
+		l.secretKey = key
+	}
+}
+
+// WithSecretKeyFile 指定保存密钥的文件，文件内容是 base64 编码的密钥，优先于环境变量
+// 每次解密都重新读取，替换文件即可轮换密钥
+func WithSecretKeyFile(path string) Option {
+	return func(l *Loader) {
+		l.secretKeyFile = path
+	}
+}
+
+// key 返回解密配置值的密钥
+// 未通过 Option 指定时依次读取环境变量 CONFIG_SECRET_KEY（base64 编码的密钥）和 CONFIG_SECRET_KEY_FILE
+func (l *Loader) key() ([]byte, error) {
+	if l.secretKey != nil {
+		return l.secretKey, nil
+	}
+	encoded, file := os.Getenv("CONFIG_SECRET_KEY"), l.secretKeyFile
+	if file == "" && encoded == "" {
+		file = os.Getenv("CONFIG_SECRET_KEY_FILE")
+	}
+	if file != "" {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("read secret key: %w", err)
+		}
+		encoded = string(b)
+	}
+	if encoded == "" {
+		return nil, errors.New("no secret key configured, set CONFIG_SECRET_KEY or CONFIG_SECRET_KEY_FILE")
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("decode secret key: %w", err)
+	}
+	return key, nil
+}
+
+// GenerateSecretKey 生成一个随机密钥，返回其 base64 编码，可以直接写入 CONFIG_SECRET_KEY 或密钥文件
+func GenerateSecretKey() (string, error) {
+	key := make([]byte, SecretKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// EncryptValue 使用 key 加密 plaintext，返回可以写入配置文件的 ENC[AES256_GCM,...] 字符串
+// 用法: v, err := config.EncryptValue(key, "my-jwt-secret")
+func EncryptValue(key []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(key, 32)
+	if err != nil {
+		return "", err
+	}
+	// 与 SOPS 一样使用 32 字节的 iv
+	iv := make([]byte, 32)
+	if _, err := rand.Read(iv); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nil, iv, []byte(plaintext), nil)
+	data, tag := sealed[:len(plaintext)], sealed[len(plaintext):]
+	b64 := base64.StdEncoding.EncodeToString
+	return fmt.Sprintf("%sdata:%s,iv:%s,tag:%s,type:str%s", encPrefix, b64(data), b64(iv), b64(tag), encSuffix), nil
+}
+
+// DecryptValue 使用 key 解密 EncryptValue 生成的字符串
+func DecryptValue(key []byte, value string) (string, error) {
+	plaintext, _, err := decryptValue(key, value, nil)
+	return plaintext, err
+}
+
+// IsEncrypted 判断 s 是否是 ENC[AES256_GCM,...] 格式的加密值
+func IsEncrypted(s string) bool {
+	return strings.HasPrefix(s, encPrefix) && strings.HasSuffix(s, encSuffix)
+}
+
+// decryptValue 解密一个加密值，返回明文和 type 字段，aad 是附加认证数据
+func decryptValue(key []byte, value string, aad []byte) (string, string, error) {
+	if !IsEncrypted(value) {
+		return "", "", errors.New("not an ENC[AES256_GCM,...] value")
+	}
+	fields := map[string]string{}
+	for _, field := range strings.Split(strings.TrimSuffix(strings.TrimPrefix(value, encPrefix), encSuffix), ",") {
+		k, v, ok := strings.Cut(field, ":")
+		if !ok {
+			return "", "", fmt.Errorf("malformed encrypted value field %q", field)
+		}
+		fields[k] = v
+	}
+	var parts [3][]byte
+	for i, name := range []string{"data", "iv", "tag"} {
+		b, err := base64.StdEncoding.DecodeString(fields[name])
+		if err != nil {
+			return "", "", fmt.Errorf("malformed encrypted value %s: %w", name, err)
+		}
+		parts[i] = b
+	}
+	data, iv, tag := parts[0], parts[1], parts[2]
+	if len(iv) == 0 {
+		return "", "", errors.New("malformed encrypted value: empty iv")
+	}
+	gcm, err := newGCM(key, len(iv))
+	if err != nil {
+		return "", "", err
+	}
+	plaintext, err := gcm.Open(nil, iv, append(data[:len(data):len(data)], tag...), aad)
+	if err != nil {
+		return "", "", errors.New("decryption failed, wrong key or tampered value")
+	}
+	return string(plaintext), fields["type"], nil
+}
+
+func newGCM(key []byte, nonceSize int) (cipher.AEAD, error) {
+	if len(key) != SecretKeySize {
+		return nil, fmt.Errorf("secret key must be %d bytes, got %d", SecretKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCMWithNonceSize(block, nonceSize)
+}
+
+// decryptTree 原地解密 tree 中所有加密值，返回每个出错的值对应的错误
+// 只有配置树中存在加密值时才读取密钥；解密失败的值保持密文
+func (l *Loader) decryptTree(tree map[string]interface{}) []error {
+	var (
+		key    []byte
+		keyErr error
+		loaded bool
+		errs   []error
+	)
+	walkStrings(tree, nil, func(path []string, s string) (interface{}, bool) {
+		if !IsEncrypted(s) {
+			return nil, false
+		}
+		if !loaded {
+			key, keyErr = l.key()
+			loaded = true
+		}
+		err := keyErr
+		var plaintext string
+		if err == nil {
+			plaintext, _, err = decryptValue(key, s, nil)
+		}
+		if err != nil {
+			errs = append(errs, &LoadError{Section: path[0], Err: fmt.Errorf("decrypt %s: %w", strings.Join(path, "."), err)})
+			return nil, false
+		}
+		l.markSensitive(path)
+		// 与 ${...} 的展开结果一样由字段类型决定如何解码
+		return scalar(plaintext), true
+	})
+	return errs
+}
+
+// walkStrings 对 node 中每个字符串值调用 fn，fn 返回 true 时用返回的值替换原来的值
+// 展开 ${...} 得到的 scalar 同样视为字符串，数组元素使用数组本身的路径
+func walkStrings(node interface{}, path []string, fn func(path []string, s string) (interface{}, bool)) {
+	visit := func(p []string, v interface{}, set func(interface{})) {
+		var s string
+		switch x := v.(type) {
+		case string:
+			s = x
+		case scalar:
+			s = string(x)
+		default:
+			walkStrings(v, p, fn)
+			return
+		}
+		if out, ok := fn(p, s); ok {
+			set(out)
+		}
+	}
+	switch n := node.(type) {
+	case map[string]interface{}:
+		for k, v := range n {
+			visit(append(path[:len(path):len(path)], k), v, func(out interface{}) { n[k] = out })
+		}
+	case []interface{}:
+		for i, v := range n {
+			visit(path, v, func(out interface{}) { n[i] = out })
+		}
+	}
+}
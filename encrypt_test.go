@@ -0,0 +1,111 @@
+package config
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testKey(t *testing.T) (string, []byte) {
+	t.Helper()
+	encoded, err := GenerateSecretKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := base64.StdEncoding.DecodeString(encoded)
+	return encoded, key
+}
+
+func TestEncryptValue(t *testing.T) {
+	_, key := testKey(t)
+	v, err := EncryptValue(key, "s3cr3t")
+	if err != nil {
+		t.Fatalf("EncryptValue failed: %v", err)
+	}
+	if !IsEncrypted(v) || strings.Contains(v, "s3cr3t") {
+		t.Fatalf("Unexpected encrypted value %s", v)
+	}
+	if got, err := DecryptValue(key, v); err != nil || got != "s3cr3t" {
+		t.Errorf("Expected s3cr3t, got %q (%v)", got, err)
+	}
+
+	_, other := testKey(t)
+	if _, err := DecryptValue(other, v); err == nil {
+		t.Error("Expected error for wrong key")
+	}
+	tampered := strings.Replace(v, "data:", "data:AA", 1)
+	if _, err := DecryptValue(key, tampered); err == nil {
+		t.Error("Expected error for tampered value")
+	}
+	if _, err := EncryptValue(key[:16], "x"); err == nil {
+		t.Error("Expected error for short key")
+	}
+}
+
+func TestDecryptOnLoad(t *testing.T) {
+	encoded, key := testKey(t)
+	password, _ := EncryptValue(key, "redis-pass")
+	port, _ := EncryptValue(key, "6380")
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml": "server:\n  port: " + port + "\n  name: app\nredis:\n  default:\n    password: " + password + "\n",
+	})
+	keyFile := filepath.Join(dir, "secret.key")
+	if err := os.WriteFile(keyFile, []byte(encoded+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_SECRET_KEY_FILE", keyFile)
+
+	l := New(WithConfigPath(filepath.Join(dir, "app.yaml")))
+	srv := RegisterTo(l, &server{})
+	rds := RegisterMapTo[*redis](l, "redis")
+	if err := l.Err(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if srv.Port != 6380 {
+		t.Errorf("Expected decrypted port 6380, got %d", srv.Port)
+	}
+	if rds.Default().Password != "redis-pass" {
+		t.Errorf("Expected decrypted password, got %q", rds.Default().Password)
+	}
+
+	// 原始配置树和历史中只有密文
+	if _, err := l.Apply([]byte("server:\n  name: updated\n"), "merge"); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	hist := l.History()
+	if data := hist[len(hist)-1].data; data["redis"].(map[string]interface{})["default"].(map[string]interface{})["password"] != password {
+		t.Error("Expected raw tree to keep the ciphertext")
+	}
+
+	// 无法解密的更新被整体拒绝
+	_, other := testKey(t)
+	bad, _ := EncryptValue(other, "x")
+	if _, err := l.Apply([]byte("server:\n  name: "+bad+"\n"), "merge"); err == nil || !strings.Contains(err.Error(), "decrypt server.name") {
+		t.Errorf("Expected decrypt error, got %v", err)
+	}
+	if srv.Name != "updated" {
+		t.Errorf("Expected name to stay updated, got %q", srv.Name)
+	}
+}
+
+func TestDecryptWithoutKey(t *testing.T) {
+	_, key := testKey(t)
+	v, _ := EncryptValue(key, "x")
+	dir := writeConfigFiles(t, map[string]string{"app.yaml": "server:\n  name: " + v + "\n"})
+	t.Setenv("CONFIG_SECRET_KEY", "")
+	t.Setenv("CONFIG_SECRET_KEY_FILE", "")
+
+	l := New(WithConfigPath(filepath.Join(dir, "app.yaml")))
+	RegisterTo(l, &server{})
+	if err := l.Err(); err == nil || !strings.Contains(err.Error(), "no secret key configured") {
+		t.Errorf("Expected missing key error, got %v", err)
+	}
+
+	l = New(WithConfigPath(filepath.Join(dir, "app.yaml")), WithSecretKey(key))
+	srv := RegisterTo(l, &server{})
+	if srv.Name != "x" {
+		t.Errorf("Expected decrypted name, got %q", srv.Name)
+	}
+}
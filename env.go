@@ -0,0 +1,198 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envPrefixOrEnv 返回环境变量覆盖使用的前缀，未通过 WithEnvPrefix 指定时读取 CONFIG_ENV_PREFIX
+func (l *Loader) envPrefixOrEnv() string {
+	if l.envPrefix != "" {
+		return l.envPrefix
+	}
+	return os.Getenv("CONFIG_ENV_PREFIX")
+}
+
+// sectionTypes 返回已注册 section 名称到其值类型的映射，用于环境变量的类型转换
+func (l *Loader) sectionTypes() map[string]reflect.Type {
+	types := make(map[string]reflect.Type, len(l.registry))
+	for _, section := range l.registry {
+		if t, ok := section.(interface{ valueType() reflect.Type }); ok {
+			types[section.SectionName()] = t.valueType()
+		} else {
+			types[section.SectionName()] = reflect.TypeOf(section)
+		}
+	}
+	return types
+}
+
+// applyEnvOverlay 将 PREFIX_A__B__C 形式的环境变量覆盖到配置树的 a.b.c 上
+// 路径的每一段按大小写不敏感匹配已有的 key 或结构体的 yaml 名称，都不存在时使用小写形式
+// 值根据 section 结构体中对应字段的类型转换，切片类型按逗号分隔
+// 返回被覆盖的配置路径到环境变量名的映射
+func applyEnvOverlay(tree map[string]interface{}, prefix string, environ []string, types map[string]reflect.Type) map[string]string {
+	set := map[string]string{}
+	prefix = strings.ToUpper(prefix) + "_"
+	// 排序保证相同的环境变量总是得到相同的结果
+	environ = append([]string(nil), environ...)
+	sort.Strings(environ)
+	for _, kv := range environ {
+		key, val, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(strings.ToUpper(key), prefix) {
+			continue
+		}
+		segs := strings.Split(key[len(prefix):], "__")
+		if segs[0] == "" {
+			continue
+		}
+		name := matchSection(tree, segs[0], types)
+		set[setEnvPath(tree, name, segs[1:], types[name], val)] = key
+	}
+	return set
+}
+
+// setEnvPath 在 node 中设置 key 及其后续路径 segs 的值并返回实际设置的路径，t 是 node[key] 对应的类型，未知时为 nil
+func setEnvPath(node map[string]interface{}, key string, segs []string, t reflect.Type, val string) string {
+	if len(segs) == 0 {
+		node[key] = coerceEnv(val, t)
+		return key
+	}
+	child, ok := toStringMap(node[key])
+	if !ok {
+		child = map[string]interface{}{}
+	}
+	node[key] = child
+	next := matchKey(child, segs[0], t)
+	return key + "." + setEnvPath(child, next, segs[1:], fieldType(t, next), val)
+}
+
+// matchSection 查找与 seg 大小写不敏感匹配的顶层 section 名称，依次尝试配置树和已注册的 section
+func matchSection(tree map[string]interface{}, seg string, types map[string]reflect.Type) string {
+	for k := range tree {
+		if strings.EqualFold(k, seg) {
+			return k
+		}
+	}
+	for name := range types {
+		if strings.EqualFold(name, seg) {
+			return name
+		}
+	}
+	return strings.ToLower(seg)
+}
+
+// matchKey 在 node 的已有 key 以及类型 t 的字段中查找与 seg 大小写不敏感匹配的名称
+func matchKey(node map[string]interface{}, seg string, t reflect.Type) string {
+	for k := range node {
+		if strings.EqualFold(k, seg) {
+			return k
+		}
+	}
+	if t = indirectType(t); t != nil && t.Kind() == reflect.Struct {
+		for i := 0; i < t.NumField(); i++ {
+			if name := yamlFieldName(t.Field(i)); name != "" && strings.EqualFold(name, seg) {
+				return name
+			}
+		}
+	}
+	return strings.ToLower(seg)
+}
+
+// fieldType 返回类型 t 中 key 对应的值类型，t 为 map 时返回元素类型
+func fieldType(t reflect.Type, key string) reflect.Type {
+	t = indirectType(t)
+	if t == nil {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Map:
+		return t.Elem()
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if yamlFieldName(t.Field(i)) == key {
+				return t.Field(i).Type
+			}
+		}
+	}
+	return nil
+}
+
+// yamlFieldName 返回结构体字段在 yaml 中的名称，与 yaml.v3 的规则一致
+func yamlFieldName(f reflect.StructField) string {
+	if f.PkgPath != "" {
+		return ""
+	}
+	tag, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	if tag == "-" {
+		return ""
+	}
+	if tag != "" {
+		return tag
+	}
+	return strings.ToLower(f.Name)
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// coerceEnv 将环境变量的字符串值转换为类型 t 所需的值
+// 无法转换时保留原字符串，由解码 section 时报告错误；类型未知时按 yaml 标量解析
+func coerceEnv(val string, t reflect.Type) interface{} {
+	t = indirectType(t)
+	if t == nil {
+		var v interface{}
+		if err := yaml.Unmarshal([]byte(val), &v); err != nil || v == nil {
+			return val
+		}
+		// 只接受标量，避免 "[a]" 之类的值被解析为数组或 map
+		switch v.(type) {
+		case map[string]interface{}, []interface{}:
+			return val
+		}
+		return v
+	}
+	if t == durationType {
+		return val
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		if val == "" {
+			return []interface{}{}
+		}
+		parts := strings.Split(val, ",")
+		out := make([]interface{}, len(parts))
+		for i, p := range parts {
+			out[i] = coerceEnv(strings.TrimSpace(p), t.Elem())
+		}
+		return out
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(val); err == nil {
+			return b
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i, err := strconv.ParseInt(val, 10, 64); err == nil {
+			return i
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if u, err := strconv.ParseUint(val, 10, 64); err == nil {
+			return u
+		}
+	case reflect.Float32, reflect.Float64:
+		if f, err := strconv.ParseFloat(val, 64); err == nil {
+			return f
+		}
+	}
+	return val
+}
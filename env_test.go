@@ -0,0 +1,108 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestEnvOverlay(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml": "config: dev\n",
+		"dev.yaml": `
+server:
+  name: app
+  port: 8080
+redis:
+  default:
+    addrs: [localhost:6379]
+    password: admin
+`,
+	})
+	t.Setenv("TEST_SERVER__PORT", "9090")
+	t.Setenv("TEST_SERVER__ALLOWORIGINS", "a.com, b.com")
+	t.Setenv("TEST_REDIS__DEFAULT__ADDRS", "a:6379,b:6379")
+	t.Setenv("TEST_REDIS__DEFAULT__DB", "3")
+	t.Setenv("TEST_REDIS__SESSION__PASSWORD", "secret")
+	t.Setenv("TEST_AUTH__JWT_SECRET", "from-env")
+	t.Setenv("OTHER_SERVER__PORT", "1")
+
+	l := New(WithConfigPath(filepath.Join(dir, "app.yaml")), WithEnvPrefix("TEST"))
+	srv := RegisterTo(l, &server{})
+	redisMap := RegisterMapTo[*redis](l, "redis")
+	authCfg := RegisterTo(l, &auth{})
+
+	if srv.Port != 9090 || srv.Name != "app" {
+		t.Errorf("Expected server.Port overridden to 9090, got %+v", srv)
+	}
+	if !reflect.DeepEqual(srv.AllowOrigins, []string{"a.com", "b.com"}) {
+		t.Errorf("Expected allowOrigins from env, got %v", srv.AllowOrigins)
+	}
+	def := redisMap.Default()
+	if def == nil || !reflect.DeepEqual(def.Addrs, []string{"a:6379", "b:6379"}) || def.DB != 3 {
+		t.Errorf("Expected redis.default overridden, got %+v", def)
+	}
+	if def != nil && def.Password != "admin" {
+		t.Errorf("Expected redis.default.password to be kept, got '%s'", def.Password)
+	}
+	if s := redisMap["session"]; s == nil || s.Password != "secret" {
+		t.Errorf("Expected redis.session created from env, got %+v", s)
+	}
+	if authCfg.JWTSecret != "from-env" {
+		t.Errorf("Expected auth.jwt_secret from env, got '%s'", authCfg.JWTSecret)
+	}
+
+	// 环境变量优先于远程更新，overwrite 也不会丢弃
+	if err := l.UpdateConfig([]byte("server:\n  port: 7070\n"), "overwrite"); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	if srv.Port != 9090 {
+		t.Errorf("Expected env override to survive overwrite, got %d", srv.Port)
+	}
+}
+
+func TestEnvOverlayDisabled(t *testing.T) {
+	t.Setenv("CONFIG_ENV_PREFIX", "")
+	t.Setenv("APP_SERVER__PORT", "9090")
+	l := newTestLoader(t)
+	srv := RegisterTo(l, &server{})
+	if srv.Port != 8080 {
+		t.Errorf("Expected env overlay to be opt-in, got port %d", srv.Port)
+	}
+}
+
+func TestEnvOverlayPrefixFromEnv(t *testing.T) {
+	t.Setenv("CONFIG_ENV_PREFIX", "APP")
+	t.Setenv("APP_SERVER__PORT", "9090")
+	l := newTestLoader(t)
+	srv := RegisterTo(l, &server{})
+	if srv.Port != 9090 {
+		t.Errorf("Expected CONFIG_ENV_PREFIX to enable overlay, got port %d", srv.Port)
+	}
+}
+
+func TestCoerceEnv(t *testing.T) {
+	tests := []struct {
+		val  string
+		typ  reflect.Type
+		want interface{}
+	}{
+		{"9090", reflect.TypeOf(0), int64(9090)},
+		{"true", reflect.TypeOf(false), true},
+		{"1.5", reflect.TypeOf(0.0), 1.5},
+		{"42", reflect.TypeOf(uint64(0)), uint64(42)},
+		{"123", reflect.TypeOf(""), "123"},
+		{"5s", durationType, "5s"},
+		{"a,b", reflect.TypeOf([]string{}), []interface{}{"a", "b"}},
+		{"1,2", reflect.TypeOf([]int{}), []interface{}{int64(1), int64(2)}},
+		{"oops", reflect.TypeOf(0), "oops"},
+		{"9090", nil, 9090},
+		{"[a]", nil, "[a]"},
+		{"text", nil, "text"},
+	}
+	for _, tt := range tests {
+		if got := coerceEnv(tt.val, tt.typ); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("coerceEnv(%q, %v) = %#v, want %#v", tt.val, tt.typ, got, tt.want)
+		}
+	}
+}
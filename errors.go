@@ -0,0 +1,116 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+// LoadError 描述加载配置时发生的一个错误，包含出错的来源、文件和 section
+type LoadError struct {
+	Source  string // 出错的来源名称，见 WithSource，配置文件出错时为空
+	File    string // 出错的配置文件路径，与文件无关时为空
+	Section string // 出错的 section 名称，与 section 无关时为空
+	Err     error  // 底层错误，通常是文件读取错误或 yaml 错误
+}
+
+func (e *LoadError) Error() string {
+	switch {
+	case e.Source != "":
+		return fmt.Sprintf("config: source %s: %v", e.Source, e.Err)
+	case e.File != "" && e.Section != "":
+		return fmt.Sprintf("config: file %s: section %s: %v", e.File, e.Section, e.Err)
+	case e.File != "":
+		return fmt.Sprintf("config: file %s: %v", e.File, e.Err)
+	case e.Section != "":
+		return fmt.Sprintf("config: section %s: %v", e.Section, e.Err)
+	}
+	return fmt.Sprintf("config: %v", e.Err)
+}
+
+func (e *LoadError) Unwrap() error {
+	return e.Err
+}
+
+// setErrors 用一次 LoadConfig 的结果替换已记录的错误
+func (l *Loader) setErrors(errs []error) {
+	l.errMu.Lock()
+	defer l.errMu.Unlock()
+	l.errs = l.errs[:0]
+	for _, err := range errs {
+		var le *LoadError
+		if errors.As(err, &le) {
+			l.errs = append(l.errs, le)
+		} else {
+			l.errs = append(l.errs, &LoadError{Err: err})
+		}
+	}
+}
+
+// addError 记录一个加载错误，err 为 nil 时忽略
+// 注册 section 时在读锁下调用，因此使用独立的锁
+func (l *Loader) addError(err error) {
+	if err == nil {
+		return
+	}
+	var le *LoadError
+	if !errors.As(err, &le) {
+		le = &LoadError{Err: err}
+	}
+	l.errMu.Lock()
+	l.errs = append(l.errs, le)
+	l.errMu.Unlock()
+}
+
+// addErrorOnce 记录一个加载错误，已经记录过相同的错误时忽略
+func (l *Loader) addErrorOnce(err error) {
+	for _, e := range l.Errors() {
+		if e.Error() == err.Error() {
+			return
+		}
+	}
+	l.addError(err)
+}
+
+// unwrapJoined 将 errors.Join 合并的错误展开，以便逐个记录
+func unwrapJoined(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
+
+// Errors 返回最近一次加载配置文件以及之后注册 section 时记录的所有错误
+func (l *Loader) Errors() []*LoadError {
+	l.errMu.Lock()
+	defer l.errMu.Unlock()
+	return append([]*LoadError(nil), l.errs...)
+}
+
+// Err 将 Errors 汇总为一个 error，没有错误时返回 nil
+func (l *Loader) Err() error {
+	errs := l.Errors()
+	joined := make([]error, len(errs))
+	for i, err := range errs {
+		joined[i] = err
+	}
+	return errors.Join(joined...)
+}
+
+// MustLoad 确保配置已加载，存在任何加载错误时 panic
+// 适用于希望在启动阶段快速失败的服务，应在注册完所有 section 后调用
+func (l *Loader) MustLoad() {
+	l.ensureLoaded()
+	if err := l.Err(); err != nil {
+		panic(err)
+	}
+}
+
+// Err 返回默认 Loader 记录的加载错误
+func Err() error {
+	return std.Err()
+}
+
+// MustLoad 确保默认 Loader 的配置已加载，存在任何加载错误时 panic
+func MustLoad() {
+	std.MustLoad()
+}
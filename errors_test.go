@@ -0,0 +1,136 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadErrors(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml":    "config: common,missing,dev\n",
+		"common.yaml": "server:\n  name: common\n  name: again\n",
+		"dev.yaml":    "server:\n  port: not-a-number\n",
+	})
+
+	l := New(WithConfigPath(filepath.Join(dir, "app.yaml")))
+	srv := RegisterTo(l, &server{})
+
+	errs := l.Errors()
+	if len(errs) != 3 {
+		t.Fatalf("Expected 3 errors, got %d: %v", len(errs), errs)
+	}
+
+	if !strings.HasSuffix(errs[0].File, "common.yaml") || !strings.Contains(errs[0].Error(), "line 3") {
+		t.Errorf("Expected common.yaml error with line number, got %v", errs[0])
+	}
+	if !strings.HasSuffix(errs[1].File, "missing.yml") {
+		t.Errorf("Expected missing.yml error, got %v", errs[1])
+	}
+	if errs[2].Section != "server" || errs[2].File != "" {
+		t.Errorf("Expected server section error, got %v", errs[2])
+	}
+
+	err := l.Err()
+	var le *LoadError
+	if !errors.As(err, &le) {
+		t.Fatalf("Expected Err() to wrap *LoadError, got %T", err)
+	}
+	if srv.Port != 0 {
+		t.Errorf("Expected server.Port to stay zero, got %d", srv.Port)
+	}
+
+	t.Logf("✅ Load errors test passed: %v", err)
+}
+
+func TestLoadConfigReturnsError(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml": "config: dev\n",
+		"dev.yaml": "server:\n  port: 8080\n",
+	})
+
+	l := New(WithConfigPath(filepath.Join(dir, "app.yaml")))
+	if err := l.LoadConfig(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	l = New(WithConfigPath(filepath.Join(dir, "app.yaml")), WithFiles("nope"))
+	err := l.LoadConfig()
+	var le *LoadError
+	if !errors.As(err, &le) || !strings.HasSuffix(le.File, "nope.yml") {
+		t.Fatalf("Expected *LoadError for nope.yml, got %v", err)
+	}
+}
+
+func TestLoadConfigKeepsBrokenFile(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml": "config: dev\nserver:\n  name: app\n  port: 1\n",
+		"dev.yaml": "server:\n  name: dev\n  port: 2\n",
+	})
+	l := New(WithConfigPath(filepath.Join(dir, "app.yaml")))
+	srv := RegisterTo(l, &server{})
+	ch, cancel := l.Watch("server")
+	defer cancel()
+
+	// 重新加载时无法解析的文件保持原来的内容，不会退回到前面文件中的值
+	os.WriteFile(filepath.Join(dir, "dev.yaml"), []byte("server:\n  port: [\n"), 0o644)
+	err := l.LoadConfig()
+	var le *LoadError
+	if !errors.As(err, &le) || !strings.HasSuffix(le.File, "dev.yaml") {
+		t.Fatalf("Expected *LoadError for dev.yaml, got %v", err)
+	}
+	if srv.Name != "dev" || srv.Port != 2 {
+		t.Errorf("Expected server to keep dev.yaml values, got %+v", srv)
+	}
+	select {
+	case <-ch:
+		t.Error("Expected no change notification")
+	default:
+	}
+}
+
+func TestMustLoad(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml": "config: dev\n",
+		"dev.yaml": "server:\n  port: 8080\n",
+	})
+
+	ok := New(WithConfigPath(filepath.Join(dir, "app.yaml")))
+	RegisterTo(ok, &server{})
+	ok.MustLoad()
+
+	bad := New(WithConfigPath(filepath.Join(dir, "app.yaml")), WithFiles("nope"))
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Fatal("Expected MustLoad to panic")
+		}
+		if err, isErr := r.(error); !isErr || !strings.Contains(err.Error(), "nope.yml") {
+			t.Errorf("Expected panic value to mention nope.yml, got %v", r)
+		}
+	}()
+	bad.MustLoad()
+}
+
+func TestLoadErrorString(t *testing.T) {
+	inner := errors.New("boom")
+	tests := []struct {
+		err  *LoadError
+		want string
+	}{
+		{&LoadError{File: "dev.yaml", Section: "server", Err: inner}, "config: file dev.yaml: section server: boom"},
+		{&LoadError{File: "dev.yaml", Err: inner}, "config: file dev.yaml: boom"},
+		{&LoadError{Section: "server", Err: inner}, "config: section server: boom"},
+		{&LoadError{Err: inner}, "config: boom"},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("Error() = %q, want %q", got, tt.want)
+		}
+		if !errors.Is(tt.err, inner) {
+			t.Errorf("Expected %v to unwrap to inner error", tt.err)
+		}
+	}
+}
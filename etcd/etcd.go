@@ -0,0 +1,352 @@
+// Package etcd 通过 etcd v3 的 JSON gateway（/v3/kv/range、/v3/watch）读取并监听配置，
+// Client 是优先级为 config.PriorityRemote 的配置来源
+//
+// 配置可以是单个 key 中保存的整个 YAML，也可以是某个前缀下的 key 树：
+// 前缀为 /app/ 时，/app/server/port 映射为 server.port，每个值按 YAML 标量解析
+//
+// 用法:
+//
+//	if err := etcd.Start(ctx); err != nil {
+//		log.Printf("etcd: %v", err)
+//	}
+package etcd
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/teatak/config/v2"
+	"github.com/teatak/config/v2/internal/remote"
+	"github.com/teatak/config/v2/sections"
+)
+
+// Config 描述要读取的 etcd 配置
+type Config struct {
+	Endpoints  []string     // 例如 http://127.0.0.1:2379，按顺序尝试，不带协议时使用 http
+	Username   string       // 开启鉴权时的用户名，为空时不认证
+	Password   string       // 开启鉴权时的密码
+	Key        string       // 保存整个 YAML 配置的 key，与 Prefix 二选一
+	Prefix     string       // key 前缀，前缀下的每个 key 对应一个配置项，不以 / 结尾时自动补上
+	Mode       string       // merge（默认）或 overwrite，同 nacos.Config
+	HTTPClient *http.Client // 默认使用 http.DefaultClient
+}
+
+// FromSection 根据 sections.Etcd 生成 Config
+func FromSection() Config {
+	e := sections.Etcd
+	return Config{
+		Endpoints: e.Endpoints,
+		Username:  e.Username,
+		Password:  e.Password.Value(),
+		Key:       e.Key,
+		Prefix:    e.Prefix,
+		Mode:      e.Mode,
+	}
+}
+
+// Client 通过 etcd v3 JSON gateway 读取和监听配置，实现 config.Source、config.Watcher 和 config.Overwriter
+type Client struct {
+	cfg Config
+
+	mu       sync.Mutex
+	token    string // 认证得到的 token
+	revision int64  // 最近一次读取时的集群 revision，监听从下一个 revision 开始
+}
+
+// NewClient 创建一个 Client，未设置的字段使用默认值
+func NewClient(cfg Config) *Client {
+	endpoints := make([]string, 0, len(cfg.Endpoints))
+	for _, ep := range cfg.Endpoints {
+		if !strings.Contains(ep, "://") {
+			ep = "http://" + ep
+		}
+		endpoints = append(endpoints, strings.TrimRight(ep, "/"))
+	}
+	cfg.Endpoints = endpoints
+	cfg.Prefix = remote.Prefix(cfg.Prefix)
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	return &Client{cfg: cfg}
+}
+
+// Start 在 sections.Etcd.Enable 为 true 时以 source 返回的名称调用默认 Loader 的 AddSource 和 WatchSource，与 nacos.Start 相同
+func Start(ctx context.Context) error {
+	if !sections.Etcd.Enable {
+		return nil
+	}
+	c := NewClient(FromSection())
+	return remote.Start(ctx, config.Default(), c.source(), c)
+}
+
+// source 返回带有 key 或前缀的来源名称，也用于错误信息，例如 "etcd:/app/"
+func (c *Client) source() string {
+	if c.cfg.Key != "" {
+		return "etcd:" + c.cfg.Key
+	}
+	return "etcd:" + c.cfg.Prefix
+}
+
+// Overwrite 在 Mode 为 overwrite 时返回 true
+func (c *Client) Overwrite() bool {
+	return c.cfg.Mode == "overwrite"
+}
+
+// Watch 监听 key 或前缀的变化，每批变化都重新读取并推送最新的内容，直到 ctx 结束
+// 连接断开时从上次读取的 revision 之后继续监听，不会漏掉变化；revision 已被压缩时重新读取
+func (c *Client) Watch(ctx context.Context) <-chan config.Event {
+	var stream *watchStream
+	return remote.Watch(ctx, func(ctx context.Context) (map[string]any, error) {
+		if stream == nil {
+			var err error
+			if stream, err = c.watch(ctx); err != nil {
+				return nil, err
+			}
+		}
+		err := stream.next()
+		if err == nil {
+			// 只关心有没有变化，重新读取可以得到一致的整体内容
+			return c.Load(ctx)
+		}
+		stream.body.Close()
+		stream = nil
+		if errors.Is(err, errCompacted) {
+			return c.Load(ctx)
+		}
+		return nil, fmt.Errorf("watch %s: %w", c.source(), err)
+	})
+}
+
+// rangeResponse 是 /v3/kv/range 的响应，int64 在 JSON gateway 中编码为字符串
+type rangeResponse struct {
+	Header struct {
+		Revision int64 `json:"revision,string"`
+	} `json:"header"`
+	Kvs []struct {
+		Key   []byte `json:"key"` // base64
+		Value []byte `json:"value"`
+	} `json:"kvs"`
+}
+
+// Load 读取配置并解析为配置树，同时记录当前 revision 用于之后的监听
+// 使用 Key 时按 yaml 或 JSON 解析该 key 的值；使用 Prefix 时把前缀下的 key 树转换为配置树
+func (c *Client) Load(ctx context.Context) (map[string]any, error) {
+	key, end := c.keyRange()
+	req := map[string]string{"key": encode(key)}
+	if end != "" {
+		req["range_end"] = encode(end)
+	}
+	var resp rangeResponse
+	if err := c.call(ctx, "/v3/kv/range", req, &resp); err != nil {
+		return nil, fmt.Errorf("get %s: %w", c.source(), err)
+	}
+	c.mu.Lock()
+	c.revision = resp.Header.Revision
+	c.mu.Unlock()
+
+	if c.cfg.Key != "" {
+		if len(resp.Kvs) == 0 {
+			return nil, fmt.Errorf("get %s: key not found", c.source())
+		}
+		data, err := config.Parse(resp.Kvs[0].Value)
+		if err != nil {
+			return nil, fmt.Errorf("get %s: %w", c.source(), err)
+		}
+		return data, nil
+	}
+	kvs := make([]remote.KV, len(resp.Kvs))
+	for i, kv := range resp.Kvs {
+		kvs[i] = remote.KV{Key: string(kv.Key), Value: kv.Value}
+	}
+	data, err := remote.Tree(c.cfg.Prefix, kvs)
+	if err != nil {
+		return nil, fmt.Errorf("get %s: %w", c.source(), err)
+	}
+	return data, nil
+}
+
+// keyRange 返回读取和监听使用的 key 和 range_end
+// 前缀以 / 结尾，range_end 把最后的 / 加一变成 0；没有前缀时读取所有 key
+func (c *Client) keyRange() (key, end string) {
+	if c.cfg.Key != "" {
+		return c.cfg.Key, ""
+	}
+	if c.cfg.Prefix == "" {
+		return "\x00", "\x00"
+	}
+	return c.cfg.Prefix, strings.TrimSuffix(c.cfg.Prefix, "/") + "0"
+}
+
+var errCompacted = errors.New("watch revision has been compacted")
+
+// watchResponse 是 /v3/watch 流中的一条消息
+type watchResponse struct {
+	Result *struct {
+		Header struct {
+			Revision int64 `json:"revision,string"`
+		} `json:"header"`
+		Created         bool              `json:"created"`
+		Canceled        bool              `json:"canceled"`
+		CompactRevision int64             `json:"compact_revision,string"`
+		CancelReason    string            `json:"cancel_reason"`
+		Events          []json.RawMessage `json:"events"`
+	} `json:"result"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// watchStream 是一个打开的 /v3/watch 监听流，只在 Watch 的 goroutine 中使用
+type watchStream struct {
+	body io.ReadCloser
+	dec  *json.Decoder
+}
+
+// watch 从上次读取的 revision 之后建立一个监听流
+func (c *Client) watch(ctx context.Context) (*watchStream, error) {
+	c.mu.Lock()
+	start := c.revision + 1
+	c.mu.Unlock()
+
+	key, end := c.keyRange()
+	create := map[string]interface{}{
+		"key":            encode(key),
+		"start_revision": fmt.Sprint(start),
+	}
+	if end != "" {
+		create["range_end"] = encode(end)
+	}
+	body, err := c.open(ctx, "/v3/watch", map[string]interface{}{"create_request": create})
+	if err != nil {
+		return nil, fmt.Errorf("watch %s: %w", c.source(), err)
+	}
+	return &watchStream{body: body, dec: json.NewDecoder(body)}, nil
+}
+
+// next 等待下一批变化，流结束或出错时返回错误，revision 已被压缩时返回 errCompacted
+func (s *watchStream) next() error {
+	for {
+		var msg watchResponse
+		if err := s.dec.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return errors.New("stream closed")
+			}
+			return err
+		}
+		switch r := msg.Result; {
+		case msg.Error != nil:
+			return errors.New(msg.Error.Message)
+		case r == nil || r.Created:
+		case r.CompactRevision != 0:
+			return errCompacted
+		case r.Canceled:
+			return fmt.Errorf("canceled: %s", r.CancelReason)
+		case len(r.Events) > 0:
+			return nil
+		}
+	}
+}
+
+// call 发送 JSON 请求并解码响应
+func (c *Client) call(ctx context.Context, path string, req, resp interface{}) error {
+	body, err := c.open(ctx, path, req)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	return json.NewDecoder(body).Decode(resp)
+}
+
+// open 依次尝试每个 endpoint 发送 JSON 请求，返回第一个成功响应的 body
+// token 失效（401）时重新认证并重试一次
+func (c *Client) open(ctx context.Context, path string, req interface{}) (io.ReadCloser, error) {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.cfg.Endpoints) == 0 {
+		return nil, errors.New("no endpoints configured")
+	}
+	var errs []error
+	for _, ep := range c.cfg.Endpoints {
+		for retry := 0; retry < 2; retry++ {
+			token, err := c.authenticate(ctx, ep, retry > 0)
+			if err != nil {
+				errs = append(errs, err)
+				break
+			}
+			r, err := http.NewRequestWithContext(ctx, http.MethodPost, ep+path, bytes.NewReader(payload))
+			if err != nil {
+				return nil, err
+			}
+			r.Header.Set("Content-Type", "application/json")
+			if token != "" {
+				r.Header.Set("Authorization", token)
+			}
+			resp, err := c.cfg.HTTPClient.Do(r)
+			if err != nil {
+				errs = append(errs, err)
+				break
+			}
+			if resp.StatusCode == http.StatusOK {
+				return resp.Body, nil
+			}
+			b, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusUnauthorized && c.cfg.Username != "" && retry == 0 {
+				continue
+			}
+			errs = append(errs, fmt.Errorf("%s: unexpected status %s: %s", ep, resp.Status, strings.TrimSpace(string(b))))
+			break
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// authenticate 返回有效的 token，未配置用户名时返回空，force 为 true 时重新认证
+func (c *Client) authenticate(ctx context.Context, endpoint string, force bool) (string, error) {
+	if c.cfg.Username == "" {
+		return "", nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !force && c.token != "" {
+		return c.token, nil
+	}
+
+	payload, _ := json.Marshal(map[string]string{"name": c.cfg.Username, "password": c.cfg.Password})
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/v3/auth/authenticate", bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	r.Header.Set("Content-Type", "application/json")
+	resp, err := c.cfg.HTTPClient.Do(r)
+	if err != nil {
+		return "", fmt.Errorf("authenticate: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("authenticate: unexpected status %s: %s", resp.Status, strings.TrimSpace(string(b)))
+	}
+	var result struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("authenticate: %w", err)
+	}
+	c.token = result.Token
+	return c.token, nil
+}
+
+func encode(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
@@ -0,0 +1,220 @@
+package etcd
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/teatak/config/v2"
+)
+
+// fakeEtcd 实现 etcd v3 JSON gateway 中认证、range 和 watch 的部分
+type fakeEtcd struct {
+	mu       sync.Mutex
+	kvs      map[string]string
+	revision int64
+	changed  chan struct{} // 每次写入时关闭并替换
+	auths    int
+}
+
+func newFakeEtcd(kvs map[string]string) *fakeEtcd {
+	return &fakeEtcd{kvs: kvs, revision: 1, changed: make(chan struct{})}
+}
+
+func (f *fakeEtcd) put(key, value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.kvs[key] = value
+	f.revision++
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+func decode(t *testing.T, s string) string {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		t.Errorf("invalid base64 %q: %v", s, err)
+	}
+	return string(b)
+}
+
+func (f *fakeEtcd) handler(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v3/auth/authenticate" {
+			var req struct{ Name, Password string }
+			json.NewDecoder(r.Body).Decode(&req)
+			if req.Name != "root" || req.Password != "secret" {
+				http.Error(w, `{"error":"authentication failed"}`, http.StatusBadRequest)
+				return
+			}
+			f.mu.Lock()
+			f.auths++
+			f.mu.Unlock()
+			fmt.Fprint(w, `{"header":{"revision":"1"},"token":"tok"}`)
+			return
+		}
+		if r.Header.Get("Authorization") != "tok" {
+			http.Error(w, `{"error":"invalid auth token"}`, http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v3/kv/range":
+			var req struct {
+				Key      string `json:"key"`
+				RangeEnd string `json:"range_end"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			key, end := decode(t, req.Key), decode(t, req.RangeEnd)
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			var keys []string
+			for k := range f.kvs {
+				if k == key || (end != "" && k >= key && k < end) {
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+			kvs := make([]map[string]string, len(keys))
+			for i, k := range keys {
+				kvs[i] = map[string]string{"key": base64.StdEncoding.EncodeToString([]byte(k)), "value": base64.StdEncoding.EncodeToString([]byte(f.kvs[k]))}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"header": map[string]string{"revision": fmt.Sprint(f.revision)},
+				"kvs":    kvs,
+			})
+		case "/v3/watch":
+			var req struct {
+				Create struct {
+					StartRevision string `json:"start_revision"`
+				} `json:"create_request"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			fmt.Fprint(w, `{"result":{"header":{"revision":"1"},"created":true}}`+"\n")
+			w.(http.Flusher).Flush()
+			for {
+				f.mu.Lock()
+				changed, rev := f.changed, f.revision
+				f.mu.Unlock()
+				if start, _ := strconv.ParseInt(req.Create.StartRevision, 10, 64); start != 0 && start <= rev {
+					fmt.Fprintf(w, `{"result":{"header":{"revision":"%d"},"events":[{"kv":{}}]}}`+"\n", rev)
+					w.(http.Flusher).Flush()
+					req.Create.StartRevision = fmt.Sprint(rev + 1)
+				}
+				select {
+				case <-changed:
+				case <-r.Context().Done():
+					return
+				}
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+type server struct {
+	Name string `yaml:"name"`
+	Port int    `yaml:"port"`
+}
+
+func TestPrefix(t *testing.T) {
+	fake := newFakeEtcd(map[string]string{
+		"/app/server/port": "9090",
+		"/apple/server/x":  "ignored",
+		"/other/key":       "ignored",
+	})
+	ts := httptest.NewServer(fake.handler(t))
+	defer ts.Close()
+
+	// 第一个 endpoint 不可用时使用下一个，前缀自动补上 /
+	c := NewClient(Config{Endpoints: []string{"127.0.0.1:1", ts.URL}, Username: "root", Password: "secret", Prefix: "/app"})
+	path := filepath.Join(t.TempDir(), "app.yaml")
+	if err := os.WriteFile(path, []byte("server:\n  name: local\n  port: 8080\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	l := config.New(config.WithConfigPath(path), config.WithSource(c.source(), config.PriorityRemote, c))
+	srv := config.RegisterTo(l, &server{})
+	if srv.Port != 9090 || srv.Name != "local" {
+		t.Errorf("Expected merged config, got %+v", srv)
+	}
+	if got := l.Dump(); len(got) != 1 {
+		t.Errorf("Expected keys outside the prefix to be ignored, got %v", got)
+	}
+	changes, cancelWatch := l.Watch("server")
+	defer cancelWatch()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	l.WatchSources(ctx)
+
+	fake.put("/app/server/name", "remote")
+	select {
+	case <-changes:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for etcd change")
+	}
+	if srv.Name != "remote" || srv.Port != 9090 {
+		t.Errorf("Expected name from etcd, got %+v", srv)
+	}
+	if got := l.Explain("server.name"); got[len(got)-1].Source != "etcd:/app/" {
+		t.Errorf("Expected name from etcd:/app/, got %v", got)
+	}
+	fake.mu.Lock()
+	auths := fake.auths
+	fake.mu.Unlock()
+	if auths != 1 {
+		t.Errorf("Expected token to be reused, got %d authentications", auths)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	fake := newFakeEtcd(map[string]string{"/config/app.yaml": "server:\n  port: 9090\n"})
+	ts := httptest.NewServer(fake.handler(t))
+	defer ts.Close()
+
+	c := NewClient(Config{Endpoints: []string{ts.URL}, Username: "root", Password: "secret", Key: "/config/app.yaml"})
+	data, err := c.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if port := data["server"].(map[string]interface{})["port"]; port != 9090 {
+		t.Errorf("Expected port 9090, got %v", data)
+	}
+
+	c = NewClient(Config{Endpoints: []string{ts.URL}, Username: "root", Password: "wrong", Key: "/config/app.yaml"})
+	if _, err := c.Load(context.Background()); err == nil || !strings.Contains(err.Error(), "authenticate") {
+		t.Errorf("Expected authentication error, got %v", err)
+	}
+	c = NewClient(Config{Endpoints: []string{ts.URL}, Username: "root", Password: "secret", Key: "/config/missing.yaml"})
+	if _, err := c.Load(context.Background()); err == nil || !strings.Contains(err.Error(), "key not found") {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+	if _, err := NewClient(Config{Key: "/x"}).Load(context.Background()); err == nil {
+		t.Error("Expected error without endpoints")
+	}
+}
+
+func TestKeyRange(t *testing.T) {
+	cases := []struct{ prefix, key, end string }{
+		{"/app/", "/app/", "/app0"},
+		{"/app", "/app/", "/app0"},
+		{"", "\x00", "\x00"},
+	}
+	for _, tc := range cases {
+		key, end := NewClient(Config{Prefix: tc.prefix}).keyRange()
+		if key != tc.key || end != tc.end {
+			t.Errorf("keyRange(%q) = %q, %q; want %q, %q", tc.prefix, key, end, tc.key, tc.end)
+		}
+	}
+}
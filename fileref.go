@@ -0,0 +1,212 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileRefPrefix 是 secret 文件引用的前缀，例如 password: file:///run/secrets/smtp
+// 只在 Secret 类型或带 secret:"true" 标签的字段上解析，其他字段中的 file:// 只是普通的字符串（例如 URL）
+// 任何字段都可以写成 password: !file /run/secrets/smtp 显式引用文件
+// 两种引用都只对本地的配置文件、命令行参数和环境变量生效，UpdateConfig 和远程来源不能让进程读取本地文件
+// 相对路径相对于进程的工作目录
+const fileRefPrefix = "file://"
+
+// fileRef 是 !file 标签引用的文件路径，保存在原始配置树中，计算配置时替换为文件内容
+type fileRef string
+
+// fileRefError 是无法读取引用的 secret 文件的错误
+type fileRefError struct {
+	path string
+	err  error
+}
+
+func (e *fileRefError) Error() string {
+	return fmt.Sprintf("resolve %s: %v", e.path, e.err)
+}
+
+func (e *fileRefError) Unwrap() error {
+	return e.err
+}
+
+// resolveFileRefs 原地把 tree 中的文件引用替换为文件内容，去掉末尾的换行
+// !file 引用总是解析，file:// 引用只在路径匹配 secret 中的字段且 local 返回 true 时解析，列表的元素使用列表本身的路径
+// 成功读取的值的路径传给 seal，返回引用的文件（去重并排序）以及每个无法读取的文件对应的 *fileRefError
+func resolveFileRefs(tree map[string]interface{}, secret [][]string, local func(path []string) bool, seal func(path []string)) ([]string, []error) {
+	var errs []error
+	seen := map[string]bool{}
+	var walk func(node interface{}, path []string)
+	resolve := func(v interface{}, path []string) (interface{}, bool) {
+		var file string
+		switch x := v.(type) {
+		case fileRef:
+			file = string(x)
+		case string, scalar:
+			f, ok := strings.CutPrefix(fmt.Sprint(x), fileRefPrefix)
+			if !ok || !slices.ContainsFunc(secret, func(p []string) bool { return matchPath(p, path) }) || !local(path) {
+				return nil, false
+			}
+			file = f
+		default:
+			walk(v, path)
+			return nil, false
+		}
+		seen[file] = true
+		b, err := os.ReadFile(file)
+		if err != nil {
+			errs = append(errs, &LoadError{Section: path[0], Err: &fileRefError{path: strings.Join(path, "."), err: err}})
+			return nil, false
+		}
+		seal(path)
+		// 与 ${...} 的展开结果一样由字段类型决定如何解码
+		return scalar(strings.TrimRight(string(b), "\r\n")), true
+	}
+	walk = func(node interface{}, path []string) {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			for k, v := range n {
+				if out, ok := resolve(v, append(path[:len(path):len(path)], k)); ok {
+					n[k] = out
+				}
+			}
+		case []interface{}:
+			for i, v := range n {
+				if out, ok := resolve(v, path); ok {
+					n[i] = out
+				}
+			}
+		}
+	}
+	walk(tree, nil)
+
+	files := make([]string, 0, len(seen))
+	for file := range seen {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files, errs
+}
+
+// localPaths 返回判断配置项的值是否来自本地的函数：环境变量覆盖、配置文件（包括 FileSource）或命令行参数
+// origins 是原始配置树的来源记录，env 是 applyEnvOverlay 覆盖的配置项；数组中的值使用数组本身的记录
+// 来自 UpdateConfig 和其他来源的值不是本地的，!file 标签在解析这些内容时已经被拒绝，见 rejectFileTags
+func localPaths(origins provenance, env map[string]string) func(path []string) bool {
+	return func(path []string) bool {
+		for i := len(path); i > 0; i-- {
+			key := strings.Join(path[:i], ".")
+			if _, ok := env[key]; ok {
+				return true
+			}
+			if chain := origins[key]; len(chain) > 0 {
+				kind := chain[len(chain)-1].Kind
+				return kind == FromFile || kind == FromFlag
+			}
+		}
+		return false
+	}
+}
+
+// keepBrokenRefs 处理无法读取的 secret 文件，调用方必须持有写锁
+// 本次更新没有修改的 section 继续使用当前的计算结果，错误记录到 Errors 中，不影响其他 section 的更新
+// 其余错误原样返回，由调用方放弃本次更新
+func (l *Loader) keepBrokenRefs(tree, effective config, errs []error) []error {
+	var out []error
+	for _, err := range errs {
+		var (
+			ref *fileRefError
+			le  *LoadError
+		)
+		if l.effective != nil && errors.As(err, &ref) && errors.As(err, &le) &&
+			reflect.DeepEqual(tree.get(le.Section), l.data.get(le.Section)) {
+			effective[le.Section] = l.effective.get(le.Section)
+			l.addErrorOnce(err)
+			continue
+		}
+		out = append(out, err)
+	}
+	return out
+}
+
+// rejectFileTags 在 node 含有 !file 标签时返回带行号的错误，用于 UpdateConfig 和远程来源的内容
+func rejectFileTags(node *yaml.Node) error {
+	if node.Tag == "!file" {
+		return fmt.Errorf("line %d: !file is only allowed in local config files", node.Line)
+	}
+	for _, n := range node.Content {
+		if err := rejectFileTags(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// markFileTags 将 v 中对应 node 里 !file 标签标量的值替换为 fileRef，v 是 node 解码的结果，只用于本地配置文件
+// 返回替换后的值，key 不全是字符串的 map 会转换为 map[string]interface{}
+func markFileTags(node *yaml.Node, v interface{}) interface{} {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 1 {
+			return markFileTags(node.Content[0], v)
+		}
+	case yaml.AliasNode:
+		return markFileTags(node.Alias, v)
+	case yaml.ScalarNode:
+		if node.Tag == "!file" {
+			return fileRef(strings.TrimSpace(node.Value))
+		}
+	case yaml.SequenceNode:
+		if s, ok := v.([]interface{}); ok && len(s) == len(node.Content) {
+			for i, n := range node.Content {
+				s[i] = markFileTags(n, s[i])
+			}
+		}
+	case yaml.MappingNode:
+		m, ok := toStringMap(v)
+		if !ok {
+			return v
+		}
+		// 显式的 key 优先于合并键 (<<) 引入的值，多个合并的 map 前面的优先
+		done := map[string]bool{}
+		var merged []*yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			k, val := node.Content[i], node.Content[i+1]
+			if k.Tag == "!!merge" {
+				merged = append(merged, val)
+				continue
+			}
+			done[k.Value] = true
+			m[k.Value] = markFileTags(val, m[k.Value])
+		}
+		var merge func(n *yaml.Node)
+		merge = func(n *yaml.Node) {
+			for n.Kind == yaml.AliasNode {
+				n = n.Alias
+			}
+			switch n.Kind {
+			case yaml.SequenceNode:
+				for _, item := range n.Content {
+					merge(item)
+				}
+			case yaml.MappingNode:
+				for i := 0; i+1 < len(n.Content); i += 2 {
+					if k := n.Content[i]; !done[k.Value] && k.Tag != "!!merge" {
+						done[k.Value] = true
+						m[k.Value] = markFileTags(n.Content[i+1], m[k.Value])
+					}
+				}
+			}
+		}
+		for _, n := range merged {
+			merge(n)
+		}
+		return m
+	}
+	return v
+}
@@ -0,0 +1,218 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestFileRefs(t *testing.T) {
+	secrets := t.TempDir()
+	password := filepath.Join(secrets, "password")
+	port := filepath.Join(secrets, "port")
+	os.WriteFile(password, []byte("s3cret\r\n\n"), 0o600)
+	os.WriteFile(port, []byte("6380\n"), 0o600)
+
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml": "server:\n  port: !file " + port + "\n  url: file://" + password + "\n" +
+			"vault:\n  main:\n    cert: file://" + password + "\n    dsn: file://" + password + "\n" +
+			"    backups: [file://" + password + "]\n    region: file://" + password + "\n",
+	})
+	l := New(WithConfigPath(filepath.Join(dir, "app.yaml")))
+	srv := RegisterTo(l, &server{})
+	v := RegisterMapTo[*vault](l, "vault")
+	if err := l.Err(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if srv.Port != 6380 {
+		t.Errorf("Expected port 6380 from file, got %d", srv.Port)
+	}
+	// file:// 只在 Secret 类型和带 secret 标签的字段上解析
+	if m := v["main"]; m.Cert != "s3cret" || m.DSN != "s3cret" || m.Backups[0] != "s3cret" || m.Region != "file://"+password {
+		t.Errorf("Unexpected vault %+v", *m)
+	}
+	if srv.Url != "file://"+password {
+		t.Errorf("Expected plain string field to keep file:// value, got %s", srv.Url)
+	}
+
+	// 原始配置保留引用，不包含文件内容
+	l.mu.RLock()
+	raw := l.data["vault"].(map[string]interface{})["main"].(map[string]interface{})["cert"]
+	files := l.secretFiles
+	l.mu.RUnlock()
+	if raw != "file://"+password {
+		t.Errorf("Expected raw reference, got %v", raw)
+	}
+	if len(files) != 2 {
+		t.Errorf("Expected 2 secret files, got %v", files)
+	}
+}
+
+func TestFileTags(t *testing.T) {
+	doc := &yaml.Node{}
+	content := "base: &base\n  a: !file /a\n  b: !file /b\n" +
+		"x:\n  <<: *base\n  b: plain\n  list: [!file /c, d]\n  1: !file /e\n"
+	if err := yaml.Unmarshal([]byte(content), doc); err != nil {
+		t.Fatal(err)
+	}
+	tree := config{}
+	if err := decodeDocument(doc, &tree, map[string]int{}); err != nil {
+		t.Fatal(err)
+	}
+	markFileTags(doc, tree)
+	x := tree["x"].(map[string]interface{})
+	if x["a"] != fileRef("/a") || x["b"] != "plain" || x["1"] != fileRef("/e") {
+		t.Errorf("Unexpected tree %#v", x)
+	}
+	if list := x["list"].([]interface{}); list[0] != fileRef("/c") || list[1] != "d" {
+		t.Errorf("Unexpected list %#v", list)
+	}
+}
+
+func TestFileRefsMissing(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml": "redis:\n  default:\n    password: plain\n",
+	})
+	l := New(WithConfigPath(filepath.Join(dir, "app.yaml")))
+	rds := RegisterMapTo[*redis](l, "redis")
+
+	missing := filepath.Join(dir, "missing")
+	os.WriteFile(filepath.Join(dir, "app.yaml"), []byte("redis:\n  default:\n    password: !file "+missing+"\n"), 0o644)
+	err := l.LoadConfig()
+	if err == nil || !strings.Contains(err.Error(), "redis.default.password") {
+		t.Fatalf("Expected resolve error, got %v", err)
+	}
+	if r := rds.Default(); r.Password != "plain" {
+		t.Errorf("Expected reload to be rejected, got %+v", r)
+	}
+}
+
+func TestFileRefsRemote(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "secret")
+	os.WriteFile(secret, []byte("s3cret\n"), 0o600)
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml": "vault:\n  main:\n    cert: pem\n",
+	})
+	l := New(WithConfigPath(filepath.Join(dir, "app.yaml")))
+	v := RegisterMapTo[*vault](l, "vault")
+
+	// UpdateConfig 和远程来源不能让进程读取本地文件
+	if _, err := l.Apply([]byte("vault:\n  main:\n    region: !file "+secret+"\n"), "merge"); err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Fatalf("Expected !file to be rejected, got %v", err)
+	}
+	if _, err := Parse([]byte("region: !file " + secret + "\n")); err == nil {
+		t.Error("Expected Parse to reject !file")
+	}
+	if _, err := l.Apply([]byte("vault:\n  main:\n    cert: file://"+secret+"\n"), "merge"); err != nil {
+		t.Fatal(err)
+	}
+	if m := v["main"]; m.Cert != Secret("file://"+secret) {
+		t.Errorf("Expected file:// from an update to stay literal, got %q", m.Cert.Value())
+	}
+	src := &fakeSource{data: map[string]any{"vault": map[string]any{"main": map[string]any{"dsn": "file://" + secret}}}}
+	if err := l.AddSource(context.Background(), "remote", PriorityRemote, src); err != nil {
+		t.Fatal(err)
+	}
+	if m := v["main"]; m.DSN != "file://"+secret {
+		t.Errorf("Expected file:// from a source to stay literal, got %q", m.DSN)
+	}
+	l.mu.RLock()
+	files := l.secretFiles
+	l.mu.RUnlock()
+	if len(files) != 0 {
+		t.Errorf("Expected no secret files, got %v", files)
+	}
+}
+
+func TestFileRefsPlainString(t *testing.T) {
+	// 普通字段中指向不存在文件的 URL 不是 secret 引用，不影响加载和之后的更新
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml": "server:\n  url: file:///etc/missing/x.json\n  port: 8080\n",
+	})
+	l := New(WithConfigPath(filepath.Join(dir, "app.yaml")))
+	srv := RegisterTo(l, &server{})
+	if err := l.Err(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := l.Apply([]byte("server:\n  port: 9090\n"), "merge"); err != nil {
+		t.Fatal(err)
+	}
+	if srv.Url != "file:///etc/missing/x.json" || srv.Port != 9090 {
+		t.Errorf("Unexpected server %+v", srv)
+	}
+}
+
+func TestFileRefsBrokenSection(t *testing.T) {
+	secrets := t.TempDir()
+	cert := filepath.Join(secrets, "cert")
+	os.WriteFile(cert, []byte("pem\n"), 0o600)
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml": "server:\n  port: 8080\nvault:\n  main:\n    cert: file://" + cert + "\n",
+	})
+	l := New(WithConfigPath(filepath.Join(dir, "app.yaml")))
+	srv := RegisterTo(l, &server{})
+	v := RegisterMapTo[*vault](l, "vault")
+	if err := l.Err(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// 引用的文件消失后，没有修改 vault 的更新仍然生效，vault 保持原来的值
+	os.Remove(cert)
+	if _, err := l.Apply([]byte("server:\n  port: 9090\n"), "merge"); err != nil {
+		t.Fatalf("Expected update of other sections to succeed, got %v", err)
+	}
+	if srv.Port != 9090 || v["main"].Cert != "pem" {
+		t.Errorf("Unexpected server %+v, vault %+v", srv, *v["main"])
+	}
+	if err := l.Err(); err == nil || !strings.Contains(err.Error(), "vault.main.cert") {
+		t.Errorf("Expected error for vault, got %v", err)
+	}
+	if _, err := l.Apply([]byte("server:\n  port: 9091\n"), "merge"); err != nil || len(l.Errors()) != 1 {
+		t.Errorf("Expected error to be recorded once, got %v %v", err, l.Errors())
+	}
+
+	// 修改 vault 的更新仍然被拒绝
+	if _, err := l.Apply([]byte("vault:\n  main:\n    region: us\n"), "merge"); err == nil {
+		t.Error("Expected update of the broken section to be rejected")
+	}
+}
+
+func TestWatchFileRefs(t *testing.T) {
+	// 模拟 Kubernetes Secret 卷：文件是指向 ..data/ 的符号链接，轮换时切换 ..data
+	secrets := t.TempDir()
+	os.MkdirAll(filepath.Join(secrets, "v1"), 0o755)
+	os.WriteFile(filepath.Join(secrets, "v1", "password"), []byte("old\n"), 0o600)
+	os.Symlink("v1", filepath.Join(secrets, "..data"))
+	os.Symlink(filepath.Join("..data", "password"), filepath.Join(secrets, "password"))
+
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml": "redis:\n  default:\n    password: !file " + filepath.Join(secrets, "password") + "\n",
+	})
+	l := New(WithConfigPath(filepath.Join(dir, "app.yaml")))
+	rds := RegisterMapTo[*redis](l, "redis")
+	ch, cancel := l.Watch("redis")
+	defer cancel()
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	l.WatchFiles(ctx, 10*time.Millisecond)
+
+	os.MkdirAll(filepath.Join(secrets, "v2"), 0o755)
+	os.WriteFile(filepath.Join(secrets, "v2", "password"), []byte("new\n"), 0o600)
+	os.Symlink("v2", filepath.Join(secrets, "..data_tmp"))
+	os.Rename(filepath.Join(secrets, "..data_tmp"), filepath.Join(secrets, "..data"))
+
+	waitChange(t, ch)
+	if r := rds.Default(); r.Password != "new" {
+		t.Errorf("Expected rotated password, got %+v", r)
+	}
+	hist := l.History()
+	if last := hist[len(hist)-1]; last.Source != "secrets" {
+		t.Errorf("Unexpected source %s", last.Source)
+	}
+}
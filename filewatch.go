@@ -0,0 +1,161 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"log"
+	"maps"
+	"os"
+	"time"
+)
+
+// DefaultWatchInterval 是 WatchFiles 默认的轮询间隔
+const DefaultWatchInterval = time.Second
+
+// watchedFiles 由读取本地文件的来源实现，返回最近一次加载涉及的文件
+type watchedFiles interface {
+	watchPaths() []string
+}
+
+// fileState 是被监听文件内容的 hash，文件不存在或无法读取时为零值
+type fileState map[string][32]byte
+
+// snapshot 读取 paths 中的每个文件并计算内容的 hash
+// 按路径读取会跟随符号链接，因此原子 rename 保存和 Kubernetes ConfigMap 的 ..data 链接切换都会体现为内容变化
+func snapshot(paths []string) fileState {
+	s := make(fileState, len(paths))
+	for _, p := range paths {
+		var sum [32]byte
+		if b, err := os.ReadFile(p); err == nil {
+			sum = sha256.Sum256(b)
+		}
+		s[p] = sum
+	}
+	return s
+}
+
+// fileWatch 是一个来源的监听状态
+type fileWatch struct {
+	applied fileState // 最近一次加载时文件的内容
+	pending fileState // 发现变化后上一轮看到的内容，连续两轮相同才重新加载
+}
+
+// watchTarget 是一组被监听的文件，以及它们变化后的重新加载方式
+type watchTarget struct {
+	name   string
+	paths  func() []string
+	reload func(ctx context.Context) error
+}
+
+// WatchFiles 监听默认 Loader 的配置文件，详见 Loader.WatchFiles
+func WatchFiles(ctx context.Context, interval time.Duration) {
+	std.WatchFiles(ctx, interval)
+}
+
+// WatchFiles 在后台每隔 interval 检查一次配置文件链、FileSource 来源的文件以及 file:// 引用的 secret 文件，直到 ctx 结束
+// interval 小于等于 0 时使用 DefaultWatchInterval
+//
+// 监听的文件随每次加载更新，入口文件的 config 字段新增的文件、之后才创建的文件都会被监听
+// 文件内容变化后要连续一个 interval 不再变化才会重新加载，编辑器的多次写入只触发一次加载
+// 重新加载以与 UpdateConfig 相同的事务方式应用并通知订阅者，配置文件记录为 "source:<来源名称>"，
+// secret 文件轮换时重新读取所有引用，记录为 "secrets"；
+// 文件无法解析或校验失败时记录到 Errors 中，配置保持不变，文件修复后再次加载
+func (l *Loader) WatchFiles(ctx context.Context, interval time.Duration) {
+	l.ensureLoaded()
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	watches := map[string]*fileWatch{}
+	for _, t := range l.watchTargets() {
+		watches[t.name] = &fileWatch{applied: snapshot(t.paths())}
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			for _, t := range l.watchTargets() {
+				l.checkFiles(ctx, t, watches)
+			}
+		}
+	}()
+}
+
+// watchTargets 返回配置栈中每个读取本地文件的来源，运行时添加的来源同样包含在内，最后是 secret 文件
+func (l *Loader) watchTargets() []watchTarget {
+	l.mu.RLock()
+	layers := l.stack.layers
+	l.mu.RUnlock()
+	var targets []watchTarget
+	for _, ly := range layers {
+		if w, ok := ly.source.(watchedFiles); ok {
+			name := ly.name
+			targets = append(targets, watchTarget{
+				name:  "source:" + name,
+				paths: w.watchPaths,
+				reload: func(ctx context.Context) error {
+					return l.reloadSource(ctx, name, nil)
+				},
+			})
+		}
+	}
+	return append(targets, watchTarget{
+		name: "secrets",
+		paths: func() []string {
+			l.mu.RLock()
+			defer l.mu.RUnlock()
+			return l.secretFiles
+		},
+		reload: func(context.Context) error {
+			return l.reresolve()
+		},
+	})
+}
+
+// reresolve 基于当前的配置栈重新计算 section 使用的配置，用于重新读取 secret 文件
+func (l *Loader) reresolve() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, _, err := l.applyStack("secrets", l.stack)
+	return err
+}
+
+// checkFiles 检查 t 的文件，内容变化且已经稳定时重新加载
+func (l *Loader) checkFiles(ctx context.Context, t watchTarget, watches map[string]*fileWatch) {
+	fw := watches[t.name]
+	cur := snapshot(t.paths())
+	if fw == nil {
+		watches[t.name] = &fileWatch{applied: cur}
+		return
+	}
+	if maps.Equal(cur, fw.applied) {
+		fw.pending = nil
+		return
+	}
+	if fw.pending == nil || !maps.Equal(cur, fw.pending) {
+		// 仍在写入，等待下一轮
+		fw.pending = cur
+		return
+	}
+
+	err := t.reload(ctx)
+	// 重新加载后监听的文件可能变化，已经看到的文件沿用本轮的内容，加载期间的写入会在下一轮发现
+	next := snapshot(t.paths())
+	for p, sum := range cur {
+		if _, ok := next[p]; ok {
+			next[p] = sum
+		}
+	}
+	fw.applied, fw.pending = next, nil
+	l.notify()
+	if err != nil {
+		log.Printf("config: %s: %v\n", t.name, err)
+		for _, e := range unwrapJoined(err) {
+			l.addError(e)
+		}
+	}
+}
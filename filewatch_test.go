@@ -0,0 +1,178 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitChange 等待 section 的变化通知
+func waitChange(t *testing.T, ch <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for reload")
+	}
+}
+
+// saveAtomic 模拟编辑器的保存方式：写入临时文件后 rename 到目标路径
+func saveAtomic(t *testing.T, path, content string) {
+	t.Helper()
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatchFiles(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml": "config: dev\n",
+		"dev.yml":  "server:\n  name: dev\n  port: 8080\n",
+	})
+	l := New(WithConfigPath(filepath.Join(dir, "app.yaml")))
+	srv := RegisterTo(l, &server{})
+	ch, cancel := l.Watch("server")
+	defer cancel()
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	l.WatchFiles(ctx, 10*time.Millisecond)
+
+	saveAtomic(t, filepath.Join(dir, "dev.yml"), "server:\n  name: dev\n  port: 9090\n")
+	waitChange(t, ch)
+	if srv.Port != 9090 {
+		t.Errorf("Expected port 9090, got %d", srv.Port)
+	}
+	hist := l.History()
+	if last := hist[len(hist)-1]; last.Source != "source:files" {
+		t.Errorf("Unexpected source %s", last.Source)
+	}
+
+	// 新加入链中的文件不存在时记录错误，创建后被加载
+	saveAtomic(t, filepath.Join(dir, "app.yaml"), "config: dev,local\n")
+	deadline := time.Now().Add(2 * time.Second)
+	for l.Err() == nil && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := l.Err(); err == nil || !strings.Contains(err.Error(), "local.yml") {
+		t.Fatalf("Expected missing file error, got %v", err)
+	}
+	saveAtomic(t, filepath.Join(dir, "local.yaml"), "server:\n  name: local\n")
+	waitChange(t, ch)
+	if srv.Name != "local" || srv.Port != 9090 {
+		t.Errorf("Expected local file merged, got %+v", srv)
+	}
+}
+
+func TestWatchFilesInvalid(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml": "server:\n  port: 8080\n",
+	})
+	path := filepath.Join(dir, "app.yaml")
+	l := New(WithConfigPath(path))
+	srv := RegisterTo(l, &server{})
+	ch, cancel := l.Watch("server")
+	defer cancel()
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	l.WatchFiles(ctx, 10*time.Millisecond)
+
+	// 无法解析的内容不会应用
+	saveAtomic(t, path, "server:\n  port: [\n")
+	deadline := time.Now().Add(2 * time.Second)
+	for l.Err() == nil && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if l.Err() == nil {
+		t.Fatal("Expected parse error to be recorded")
+	}
+	if srv.Port != 8080 {
+		t.Errorf("Expected port to stay 8080, got %d", srv.Port)
+	}
+
+	saveAtomic(t, path, "server:\n  port: 9090\n")
+	waitChange(t, ch)
+	if srv.Port != 9090 {
+		t.Errorf("Expected port 9090, got %d", srv.Port)
+	}
+}
+
+// TestWatchFilesSymlinkSwap 模拟 Kubernetes ConfigMap 的更新方式：
+// app.yaml -> ..data/app.yaml，..data 指向带时间戳的目录，更新时原子替换 ..data
+func TestWatchFilesSymlinkSwap(t *testing.T) {
+	dir := t.TempDir()
+	writeVersion := func(name, content string) {
+		if err := os.Mkdir(filepath.Join(dir, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name, "app.yaml"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeVersion("..v1", "server:\n  port: 8080\n")
+	if err := os.Symlink("..v1", filepath.Join(dir, "..data")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	if err := os.Symlink(filepath.Join("..data", "app.yaml"), filepath.Join(dir, "app.yaml")); err != nil {
+		t.Fatal(err)
+	}
+
+	l := New(WithConfigPath(filepath.Join(dir, "app.yaml")))
+	srv := RegisterTo(l, &server{})
+	ch, cancel := l.Watch("server")
+	defer cancel()
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	l.WatchFiles(ctx, 10*time.Millisecond)
+
+	writeVersion("..v2", "server:\n  port: 9090\n")
+	if err := os.Symlink("..v2", filepath.Join(dir, "..data_tmp")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+	waitChange(t, ch)
+	if srv.Port != 9090 {
+		t.Errorf("Expected port 9090, got %d", srv.Port)
+	}
+}
+
+// TestWatchFilesDebounce 测试连续的写入只触发一次加载
+func TestWatchFilesDebounce(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml": "server:\n  port: 1\n",
+	})
+	path := filepath.Join(dir, "app.yaml")
+	l := New(WithConfigPath(path))
+	srv := RegisterTo(l, &server{})
+	ch, cancel := l.Watch("server")
+	defer cancel()
+	before := len(l.History())
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	l.WatchFiles(ctx, 50*time.Millisecond)
+
+	for i := 2; i <= 9; i++ {
+		saveAtomic(t, path, fmt.Sprintf("server:\n  port: %d\n", i))
+		time.Sleep(5 * time.Millisecond)
+	}
+	waitChange(t, ch)
+	time.Sleep(200 * time.Millisecond)
+	if srv.Port != 9 {
+		t.Errorf("Expected final port 9, got %d", srv.Port)
+	}
+	if got := len(l.History()) - before; got != 1 {
+		t.Errorf("Expected one reload, got %d", got)
+	}
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// defaultHistorySize 是未通过 WithHistorySize 指定时保留的历史版本数
+const defaultHistorySize = 10
+
+// Revision 是配置历史中的一个版本
+type Revision struct {
+	Version int       // 从 1 开始递增的版本号
+	Time    time.Time // 生效时间
+	Source  string    // 来源，例如加载的文件列表、"update" 或 "rollback:3"
+	Changes []Change  // 相对于上一个版本的变化，敏感项已脱敏
+
+	data  config // 该版本的原始配置树，提交后不再修改
+	stack stack  // 该版本所有来源的内容
+}
+
+// String 以 "v3 update 2006-01-02T15:04:05Z [~ server.port: 8080 -> 9090]" 的形式描述版本
+// 只输出脱敏后的变化，打印 Revision 不会带出原始配置树中的明文
+func (r Revision) String() string {
+	return fmt.Sprintf("v%d %s %s %v", r.Version, r.Source, r.Time.Format(time.RFC3339), r.Changes)
+}
+
+// WithHistorySize 指定保留的历史版本数，默认为 10，小于 1 时按 1 处理
+func WithHistorySize(n int) Option {
+	return func(l *Loader) {
+		if n < 1 {
+			n = 1
+		}
+		l.historySize = n
+	}
+}
+
+// record 记录 next 成为新的当前版本并返回该版本，changes 是 next 相对于 l.data 的变化
+// 调用方必须持有写锁，且在替换 l.data 之前调用
+func (l *Loader) record(source string, changes []Change, next config, st stack) Revision {
+	size := l.historySize
+	if size == 0 {
+		size = defaultHistorySize
+	}
+	l.version++
+	rev := Revision{
+		Version: l.version,
+		Time:    time.Now(),
+		Source:  source,
+		Changes: redactChanges(changes, l.isSecret()),
+		data:    next,
+		stack:   st,
+	}
+	l.history = append(l.history, rev)
+	if over := len(l.history) - size; over > 0 {
+		l.history = append(l.history[:0:0], l.history[over:]...)
+	}
+	return rev
+}
+
+// History 返回该 Loader 保留的历史版本，按版本号从旧到新排列，最后一个是当前版本
+func (l *Loader) History() []Revision {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return append([]Revision(nil), l.history...)
+}
+
+// Rollback 将配置恢复到历史中的 version 版本
+// 与 UpdateConfig 走相同的展开、校验、提交和通知流程，并记录为一个新版本
+func (l *Loader) Rollback(version int) error {
+	err := l.rollback(version)
+	l.notify()
+	return err
+}
+
+func (l *Loader) rollback(version int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, rev := range l.history {
+		if rev.Version == version {
+			_, _, err := l.applyStack(fmt.Sprintf("rollback:%d", version), rev.stack)
+			return err
+		}
+	}
+	return fmt.Errorf("config: version %d not found in history", version)
+}
+
+// History 返回默认 Loader 的历史版本
+func History() []Revision {
+	return std.History()
+}
+
+// Rollback 将默认 Loader 的配置恢复到历史中的 version 版本
+func Rollback(version int) error {
+	return std.Rollback(version)
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHistoryAndRollback(t *testing.T) {
+	l := newTestLoader(t)
+	srv := RegisterTo(l, &server{})
+
+	hist := l.History()
+	if len(hist) != 1 || hist[0].Version != 1 || !strings.HasSuffix(hist[0].Source, "dev.yaml") {
+		t.Fatalf("Expected initial revision from files, got %+v", hist)
+	}
+
+	if err := l.UpdateConfig([]byte("server:\n  port: 9090\n"), "merge"); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	if err := l.UpdateConfig([]byte("server:\n  port: 7070\n  name: broken\n"), "merge"); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	// 失败的更新不会记录到历史中
+	if err := l.UpdateConfig([]byte("server:\n  port: nope\n"), "merge"); err == nil {
+		t.Fatal("Expected UpdateConfig to fail")
+	}
+
+	hist = l.History()
+	if len(hist) != 3 {
+		t.Fatalf("Expected 3 revisions, got %d", len(hist))
+	}
+	last := hist[2]
+	if last.Version != 3 || last.Source != "update" || last.Time.IsZero() {
+		t.Errorf("Unexpected last revision: %+v", last)
+	}
+	if len(last.Changes) != 2 || last.Changes[0].Path != "server.name" || last.Changes[1].Path != "server.port" {
+		t.Errorf("Unexpected changes: %+v", last.Changes)
+	}
+
+	ch, cancel := l.Watch("server")
+	defer cancel()
+
+	if err := l.Rollback(2); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if srv.Port != 9090 || srv.Name != "app" {
+		t.Errorf("Expected server from version 2, got %+v", srv)
+	}
+	select {
+	case <-ch:
+	default:
+		t.Error("Expected rollback to notify watchers")
+	}
+
+	hist = l.History()
+	if last := hist[len(hist)-1]; last.Version != 4 || last.Source != "rollback:2" {
+		t.Errorf("Expected rollback to be recorded as version 4, got %+v", last)
+	}
+
+	if err := l.Rollback(42); err == nil {
+		t.Error("Expected error for unknown version")
+	}
+}
+
+func TestHistorySize(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{"app.yaml": "server:\n  port: 1\n"})
+	l := New(WithConfigPath(filepath.Join(dir, "app.yaml")), WithHistorySize(2))
+	srv := RegisterTo(l, &server{})
+
+	for _, port := range []string{"2", "3", "4"} {
+		if err := l.UpdateConfig([]byte("server:\n  port: "+port+"\n"), "merge"); err != nil {
+			t.Fatalf("UpdateConfig failed: %v", err)
+		}
+	}
+	hist := l.History()
+	if len(hist) != 2 || hist[0].Version != 3 || hist[1].Version != 4 {
+		t.Fatalf("Expected versions [3 4], got %+v", hist)
+	}
+	if err := l.Rollback(1); err == nil {
+		t.Error("Expected evicted version to be unavailable")
+	}
+	if err := l.Rollback(3); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if srv.Port != 3 {
+		t.Errorf("Expected port 3 after rollback, got %d", srv.Port)
+	}
+}
+
+func TestHistorySkipsNoop(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{"app.yaml": "server:\n  port: 1\n"})
+	l := New(WithConfigPath(filepath.Join(dir, "app.yaml")), WithHistorySize(3))
+	RegisterTo(l, &server{})
+	if err := l.UpdateConfig([]byte("server:\n  port: 2\n"), "merge"); err != nil {
+		t.Fatal(err)
+	}
+	ch, cancel := l.Watch("server")
+	defer cancel()
+
+	// 没有变化的更新和重新加载不记录版本，也不通知，真正的变化留在历史中
+	for i := 0; i < 3; i++ {
+		report, err := l.Apply([]byte("server:\n  port: 2\n"), "merge")
+		if err != nil || len(report.Changes) != 0 {
+			t.Fatalf("Expected no changes, got %+v %v", report, err)
+		}
+		if err := l.LoadConfig(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hist := l.History()
+	if len(hist) != 2 || hist[1].Version != 2 || hist[1].Changes[0].Path != "server.port" {
+		t.Fatalf("Expected only the real revisions, got %+v", hist)
+	}
+	select {
+	case <-ch:
+		t.Error("Expected no change notification")
+	default:
+	}
+}
@@ -0,0 +1,201 @@
+// Package httpconfig 定期从 HTTP(S) 地址读取配置，Client 是优先级为 config.PriorityRemote 的配置来源
+//
+// 轮询请求带上 If-None-Match / If-Modified-Since，服务端返回 304 时不读取内容；
+// 返回 200 但内容的 hash 与上次相同时也不会重复推送
+//
+// 用法:
+//
+//	if err := httpconfig.Start(ctx); err != nil {
+//		log.Printf("httpconfig: %v", err)
+//	}
+package httpconfig
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/teatak/config/v2"
+	"github.com/teatak/config/v2/internal/remote"
+	"github.com/teatak/config/v2/sections"
+)
+
+// DefaultInterval 是默认的轮询间隔
+const DefaultInterval = 30 * time.Second
+
+// Config 描述要轮询的配置地址
+type Config struct {
+	URL      string        // 配置地址，响应内容是 yaml 或 JSON
+	Interval time.Duration // 轮询间隔，默认 30s
+	Token    string        // bearer token，与 Username / Password 二选一
+	Username string        // basic auth 用户名
+	Password string        // basic auth 密码
+	CAFile   string        // 自定义 CA 证书（PEM），追加到系统证书之后
+	Mode     string        // merge（默认）或 overwrite，同 nacos.Config
+	// HTTPClient 默认使用带 10s 超时的新 http.Client，设置了 CAFile 时会替换其 Transport
+	HTTPClient *http.Client
+}
+
+// FromSection 根据 sections.HttpConfig 生成 Config
+func FromSection() Config {
+	s := sections.HttpConfig
+	return Config{
+		URL:      s.URL,
+		Interval: s.Interval,
+		Token:    s.Token.Value(),
+		Username: s.Username,
+		Password: s.Password.Value(),
+		CAFile:   s.CAFile,
+		Mode:     s.Mode,
+	}
+}
+
+// Client 定期读取一个配置地址，实现 config.Source、config.Watcher 和 config.Overwriter
+type Client struct {
+	cfg Config
+
+	mu           sync.Mutex
+	etag         string   // 上次响应的 ETag
+	lastModified string   // 上次响应的 Last-Modified
+	hash         [32]byte // 上次读取的内容的 hash
+}
+
+// NewClient 创建一个 Client，未设置的字段使用默认值，CAFile 无法读取或解析时返回错误
+func NewClient(cfg Config) (*Client, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("httpconfig: url is required")
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultInterval
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("httpconfig: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("httpconfig: no certificates found in %s", cfg.CAFile)
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+		client := *cfg.HTTPClient
+		client.Transport = transport
+		cfg.HTTPClient = &client
+	}
+	return &Client{cfg: cfg}, nil
+}
+
+// Start 在 sections.HttpConfig.Enable 为 true 时以名称 http 调用默认 Loader 的 AddSource 和 WatchSource，与 nacos.Start 相同
+func Start(ctx context.Context) error {
+	if !sections.HttpConfig.Enable {
+		return nil
+	}
+	c, err := NewClient(FromSection())
+	if err != nil {
+		return err
+	}
+	return remote.Start(ctx, config.Default(), "http", c)
+}
+
+// Overwrite 在 Mode 为 overwrite 时返回 true
+func (c *Client) Overwrite() bool {
+	return c.cfg.Mode == "overwrite"
+}
+
+// Load 读取配置并解析为配置树，总是读取完整的内容，不发送条件请求
+func (c *Client) Load(ctx context.Context) (map[string]any, error) {
+	data, _, err := c.fetch(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	return c.parse(data)
+}
+
+// Watch 每隔 Interval 发起一次条件请求，内容有变化时推送最新的内容，直到 ctx 结束
+func (c *Client) Watch(ctx context.Context) <-chan config.Event {
+	return remote.Watch(ctx, func(ctx context.Context) (map[string]any, error) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(c.cfg.Interval):
+		}
+		data, changed, err := c.fetch(ctx, true)
+		if err != nil || !changed {
+			return nil, err
+		}
+		return c.parse(data)
+	})
+}
+
+func (c *Client) parse(data []byte) (map[string]any, error) {
+	tree, err := config.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", c.cfg.URL, err)
+	}
+	return tree, nil
+}
+
+// fetch 读取一次配置，返回内容以及内容是否与上次不同，conditional 为 true 时带上上次响应的 ETag 和 Last-Modified
+func (c *Client) fetch(ctx context.Context, conditional bool) ([]byte, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.cfg.URL, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	switch {
+	case c.cfg.Token != "":
+		req.Header.Set("Authorization", "Bearer "+c.cfg.Token)
+	case c.cfg.Username != "":
+		req.SetBasicAuth(c.cfg.Username, c.cfg.Password)
+	}
+	c.mu.Lock()
+	if conditional && c.etag != "" {
+		req.Header.Set("If-None-Match", c.etag)
+	}
+	if conditional && c.lastModified != "" {
+		req.Header.Set("If-Modified-Since", c.lastModified)
+	}
+	c.mu.Unlock()
+
+	resp, err := c.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, false, fmt.Errorf("get %s: %w", c.cfg.URL, err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return nil, false, nil
+	case http.StatusOK:
+	default:
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, false, fmt.Errorf("get %s: unexpected status %s: %s", c.cfg.URL, resp.Status, strings.TrimSpace(string(b)))
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, fmt.Errorf("get %s: %w", c.cfg.URL, err)
+	}
+
+	sum := sha256.Sum256(data)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.etag = resp.Header.Get("ETag")
+	c.lastModified = resp.Header.Get("Last-Modified")
+	changed := sum != c.hash
+	c.hash = sum
+	return data, changed, nil
+}
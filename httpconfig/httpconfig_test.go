@@ -0,0 +1,154 @@
+package httpconfig
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/teatak/config/v2"
+)
+
+// fakeServer 提供带 ETag 和 Last-Modified 的配置内容
+type fakeServer struct {
+	mu       sync.Mutex
+	content  string
+	etag     string
+	requests int
+	notMod   int // 返回 304 的次数
+}
+
+func (f *fakeServer) set(content, etag string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.content, f.etag = content, etag
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	user, pass, ok := r.BasicAuth()
+	if r.Header.Get("Authorization") != "Bearer tok" && !(ok && user == "admin" && pass == "secret") {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests++
+	lastModified := "Mon, 12 Oct 2026 08:00:00 GMT"
+	if r.Header.Get("If-None-Match") == f.etag && r.Header.Get("If-Modified-Since") == lastModified {
+		f.notMod++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", f.etag)
+	w.Header().Set("Last-Modified", lastModified)
+	fmt.Fprint(w, f.content)
+}
+
+type server struct {
+	Name string `yaml:"name"`
+	Port int    `yaml:"port"`
+}
+
+// writeCA 将测试服务器的证书写入 PEM 文件
+func writeCA(t *testing.T, ts *httptest.Server) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	b := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestWatch(t *testing.T) {
+	fake := &fakeServer{content: "server:\n  port: 9090\n", etag: `"v1"`}
+	ts := httptest.NewTLSServer(fake)
+	defer ts.Close()
+
+	c, err := NewClient(Config{URL: ts.URL + "/app.yaml", Token: "tok", CAFile: writeCA(t, ts), Interval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "app.yaml")
+	if err := os.WriteFile(path, []byte("server:\n  name: local\n  port: 8080\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	l := config.New(config.WithConfigPath(path), config.WithSource("http", config.PriorityRemote, c))
+	srv := config.RegisterTo(l, &server{})
+	if srv.Port != 9090 || srv.Name != "local" {
+		t.Errorf("Expected merged config, got %+v", srv)
+	}
+	versions := len(l.History())
+	changes, cancelWatch := l.Watch("server")
+	defer cancelWatch()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	l.WatchSources(ctx)
+
+	// 未变化时服务端返回 304；ETag 变化但内容相同时不重复推送
+	time.Sleep(50 * time.Millisecond)
+	fake.set("server:\n  port: 9090\n", `"v2"`)
+	time.Sleep(50 * time.Millisecond)
+	if got := len(l.History()); got != versions {
+		t.Errorf("Expected no new revision, got %d -> %d", versions, got)
+	}
+	fake.mu.Lock()
+	notMod := fake.notMod
+	fake.mu.Unlock()
+	if notMod == 0 {
+		t.Error("Expected conditional requests to get 304 responses")
+	}
+
+	fake.set("{\"server\": {\"port\": 7070}}", `"v3"`)
+	select {
+	case <-changes:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for poll")
+	}
+	if srv.Port != 7070 || srv.Name != "local" {
+		t.Errorf("Expected polled config, got %+v", srv)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	ts := httptest.NewTLSServer(&fakeServer{content: "server:\n  port: 9090\n", etag: `"v1"`})
+	defer ts.Close()
+
+	c, _ := NewClient(Config{URL: ts.URL, Username: "admin", Password: "secret", CAFile: writeCA(t, ts)})
+	// Load 不发送条件请求，重复调用也能得到完整的内容
+	for i := 0; i < 2; i++ {
+		data, err := c.Load(context.Background())
+		if err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		if port := data["server"].(map[string]interface{})["port"]; port != 9090 {
+			t.Errorf("Expected port 9090, got %v", data)
+		}
+	}
+
+	// 没有自定义 CA 时无法验证测试证书
+	c, _ = NewClient(Config{URL: ts.URL, Token: "tok"})
+	if _, err := c.Load(context.Background()); err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Errorf("Expected certificate error, got %v", err)
+	}
+	c, _ = NewClient(Config{URL: ts.URL, CAFile: writeCA(t, ts)})
+	if _, err := c.Load(context.Background()); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected unauthorized error, got %v", err)
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.pem")
+	os.WriteFile(bad, []byte("not a certificate"), 0o644)
+	if _, err := NewClient(Config{URL: ts.URL, CAFile: bad}); err == nil {
+		t.Error("Expected error for invalid CA file")
+	}
+	if _, err := NewClient(Config{}); err == nil {
+		t.Error("Expected error without url")
+	}
+}
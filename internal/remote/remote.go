@@ -0,0 +1,119 @@
+// Package remote 包含 nacos、etcd、consul 和 httpconfig 等远程配置来源共用的部分：
+// 监听循环、key 树到配置树的映射，以及添加到 Loader 的流程
+package remote
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/teatak/config/v2"
+)
+
+// 出错后重试的间隔从 minBackoff 开始翻倍，最长为 maxBackoff
+var (
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+)
+
+// Poll 等待来源的下一次变化并返回最新的完整配置树，等待结束但没有变化（例如长轮询超时）时返回 nil
+type Poll func(ctx context.Context) (map[string]any, error)
+
+// Watch 在后台反复调用 poll 实现 config.Watcher，直到 ctx 结束后关闭返回的通道
+// 新内容和错误都作为 config.Event 推送；出错后以指数退避重试，成功一次后间隔重置
+func Watch(ctx context.Context, poll Poll) <-chan config.Event {
+	events := make(chan config.Event)
+	go func() {
+		defer close(events)
+		backoff := minBackoff
+		for {
+			data, err := poll(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			if err == nil {
+				backoff = minBackoff
+				if data != nil && !send(ctx, events, config.Event{Data: data}) {
+					return
+				}
+				continue
+			}
+			if !send(ctx, events, config.Event{Err: err}) {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, maxBackoff)
+		}
+	}()
+	return events
+}
+
+func send(ctx context.Context, events chan<- config.Event, ev config.Event) bool {
+	select {
+	case events <- ev:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// KV 是 key 树中的一个 key 及其原始值
+type KV struct {
+	Key   string
+	Value []byte
+}
+
+// Prefix 返回以 "/" 结尾的 key 前缀，保证前缀 /app 不会匹配 /apple/x，空前缀保持不变
+func Prefix(prefix string) string {
+	if prefix == "" || strings.HasSuffix(prefix, "/") {
+		return prefix
+	}
+	return prefix + "/"
+}
+
+// Tree 将 prefix 下的 key 树转换为配置树：去掉 prefix 后按 "/" 分隔为路径，每个值按 YAML 标量解析
+// 例如 prefix 为 /app/ 时 /app/server/port 映射为 server.port；不在 prefix 下的 key 和以 "/" 结尾的目录 key 被忽略
+func Tree(prefix string, kvs []KV) (map[string]any, error) {
+	tree := map[string]any{}
+	for _, kv := range kvs {
+		path, ok := strings.CutPrefix(kv.Key, prefix)
+		if !ok || path == "" || strings.HasSuffix(path, "/") {
+			continue
+		}
+		var v any
+		if err := yaml.Unmarshal(kv.Value, &v); err != nil {
+			return nil, fmt.Errorf("key %s: %w", kv.Key, err)
+		}
+		setPath(tree, strings.Split(strings.Trim(path, "/"), "/"), v)
+	}
+	return tree, nil
+}
+
+// setPath 在 tree 中按 path 设置值，中间缺少的层级创建为 map，已有的标量被替换
+func setPath(tree map[string]any, path []string, v any) {
+	for _, seg := range path[:len(path)-1] {
+		child, ok := tree[seg].(map[string]any)
+		if !ok {
+			child = map[string]any{}
+			tree[seg] = child
+		}
+		tree = child
+	}
+	tree[path[len(path)-1]] = v
+}
+
+// Start 以 config.PriorityRemote 将 src 添加到 l 的配置栈中，并在后台监听它的变化直到 ctx 结束
+// 首次加载或应用失败时不添加来源并返回错误，调用方可以稍后重试
+func Start(ctx context.Context, l *config.Loader, name string, src config.Source) error {
+	if err := l.AddSource(ctx, name, config.PriorityRemote, src); err != nil {
+		return err
+	}
+	return l.WatchSource(ctx, name)
+}
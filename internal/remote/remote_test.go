@@ -0,0 +1,120 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/teatak/config/v2"
+)
+
+func TestWatch(t *testing.T) {
+	minBackoff, maxBackoff = time.Millisecond, 4*time.Millisecond
+	defer func() { minBackoff, maxBackoff = time.Second, time.Minute }()
+
+	boom := errors.New("boom")
+	results := []struct {
+		data map[string]any
+		err  error
+	}{
+		{nil, nil}, // 没有变化，不推送
+		{map[string]any{"a": 1}, nil},
+		{nil, boom},
+		{map[string]any{"a": 2}, nil},
+	}
+	calls := 0
+	ctx, cancel := context.WithCancel(context.Background())
+	events := Watch(ctx, func(ctx context.Context) (map[string]any, error) {
+		if calls == len(results) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		r := results[calls]
+		calls++
+		return r.data, r.err
+	})
+
+	var got []config.Event
+	for len(got) < 3 {
+		got = append(got, <-events)
+	}
+	if got[0].Data["a"] != 1 || !errors.Is(got[1].Err, boom) || got[2].Data["a"] != 2 {
+		t.Errorf("Unexpected events %v", got)
+	}
+	cancel()
+	select {
+	case ev, ok := <-events:
+		if ok {
+			t.Errorf("Unexpected event after cancel %v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected events to be closed after cancel")
+	}
+}
+
+func TestTree(t *testing.T) {
+	tree, err := Tree(Prefix("app"), []KV{
+		{"app/", nil},
+		{"app/server/", nil},
+		{"app/server/port", []byte("9090")},
+		{"app/server/tags", []byte("[a, b]")},
+		{"app/log", []byte("info")},
+		{"app/log/level", []byte("debug")}, // 标量被替换为 map
+		{"apple/x", []byte("1")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"server": map[string]any{"port": 9090, "tags": []any{"a", "b"}},
+		"log":    map[string]any{"level": "debug"},
+	}
+	if !reflect.DeepEqual(tree, want) {
+		t.Errorf("Expected %v, got %v", want, tree)
+	}
+	if _, err := Tree("", []KV{{"a", []byte("[")}}); err == nil || !strings.Contains(err.Error(), "key a") {
+		t.Errorf("Expected error naming the key, got %v", err)
+	}
+}
+
+// source 是测试用的远程来源
+type source struct {
+	events chan config.Event
+}
+
+func (s *source) Load(ctx context.Context) (map[string]any, error) {
+	return map[string]any{"server": map[string]any{"port": 9090}}, nil
+}
+
+func (s *source) Watch(ctx context.Context) <-chan config.Event {
+	return s.events
+}
+
+func TestStart(t *testing.T) {
+	l := config.New(config.WithConfigPath(filepath.Join(t.TempDir(), "app.yaml")))
+	src := &source{events: make(chan config.Event, 1)}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := Start(ctx, l, "remote", src); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if got := l.Explain("server.port"); len(got) != 1 || got[0].Source != "remote" {
+		t.Errorf("Expected port from remote, got %v", got)
+	}
+
+	ch, stop := l.Watch("server")
+	defer stop()
+	src.events <- config.Event{Data: map[string]any{"server": map[string]any{"port": 7070}}}
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for change")
+	}
+	if err := Start(ctx, l, "remote", src); err == nil {
+		t.Error("Expected error for duplicate source")
+	}
+}
@@ -0,0 +1,277 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// interpolator 展开配置树中字符串值里的 ${...} 引用
+//
+//	${ENV}              环境变量，未设置时为空字符串
+//	${ENV:-fallback}    环境变量未设置或为空时使用 fallback
+//	${ENV:?message}     环境变量未设置或为空时报错
+//	${server.url}       引用其他配置项（名称中包含 "."），不存在时报错
+//	$${literal}         转义，得到字面量 ${literal}
+//
+// 整个值只包含一个配置项引用时保留被引用值的类型，例如 port: ${server.port} 仍然是数字；
+// 其余展开结果是不带类型的标量，由 section 字段的类型决定如何解码，例如 port: ${PORT:-8080}
+type interpolator struct {
+	root      map[string]interface{}
+	lookupEnv func(string) (string, bool)
+	done      map[string]bool
+	resolving []string
+}
+
+// interpolateTree 原地展开 tree 中所有字符串值，返回每个出错的值对应的错误
+func interpolateTree(tree map[string]interface{}, lookupEnv func(string) (string, bool)) []error {
+	in := &interpolator{root: tree, lookupEnv: lookupEnv, done: map[string]bool{}}
+	var errs []error
+	in.walk(tree, nil, &errs)
+	return errs
+}
+
+func (in *interpolator) walk(node interface{}, path []string, errs *[]error) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		for k, v := range n {
+			p := append(path[:len(path):len(path)], k)
+			if _, ok := v.(string); ok {
+				if _, err := in.resolvePath(p); err != nil {
+					*errs = append(*errs, &LoadError{Section: p[0], Err: err})
+				}
+				continue
+			}
+			in.walk(v, p, errs)
+		}
+	case []interface{}:
+		for i, v := range n {
+			p := append(path[:len(path):len(path)], strconv.Itoa(i))
+			if _, ok := v.(string); ok {
+				if _, err := in.resolvePath(p); err != nil {
+					*errs = append(*errs, &LoadError{Section: p[0], Err: err})
+				}
+				continue
+			}
+			in.walk(v, p, errs)
+		}
+	}
+}
+
+// resolvePath 展开 path 处的值并写回配置树，返回展开后的值
+func (in *interpolator) resolvePath(path []string) (interface{}, error) {
+	key := strings.Join(path, ".")
+	parent, last, v, ok := lookupPath(in.root, path)
+	if !ok {
+		return nil, fmt.Errorf("key %s not found", key)
+	}
+	s, isString := v.(string)
+	if !isString || in.done[key] {
+		return v, nil
+	}
+	for i, r := range in.resolving {
+		if r == key {
+			chain := append(append([]string(nil), in.resolving[i:]...), key)
+			return nil, fmt.Errorf("reference cycle detected: %s", strings.Join(chain, " -> "))
+		}
+	}
+
+	in.resolving = append(in.resolving, key)
+	out, err := in.expand(s)
+	in.resolving = in.resolving[:len(in.resolving)-1]
+	if err != nil {
+		if len(in.resolving) == 0 {
+			return nil, fmt.Errorf("interpolate %s: %w", key, err)
+		}
+		return nil, err
+	}
+	setChild(parent, last, out)
+	in.done[key] = true
+	return out, nil
+}
+
+// expand 展开字符串 s 中的所有引用
+func (in *interpolator) expand(s string) (interface{}, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	// 整个值就是一个引用时保留被引用值的类型
+	if expr, rest, ok := cutReference(s); ok && rest == "" && strings.HasPrefix(s, "${") {
+		return in.evaluate(expr)
+	}
+
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			b.WriteString(s)
+			return scalar(b.String()), nil
+		}
+		// $${ 是转义
+		if i > 0 && s[i-1] == '$' {
+			b.WriteString(s[:i])
+			b.WriteString("{")
+			s = s[i+2:]
+			continue
+		}
+		b.WriteString(s[:i])
+		expr, rest, ok := cutReference(s[i:])
+		if !ok {
+			return nil, fmt.Errorf("unterminated reference in %q", s)
+		}
+		v, err := in.evaluate(expr)
+		if err != nil {
+			return nil, err
+		}
+		if v != nil {
+			b.WriteString(fmt.Sprint(v))
+		}
+		s = rest
+	}
+}
+
+// scalar 是展开后的字符串值，序列化为不带类型标签的 yaml 标量
+// 解码时由目标字段决定类型，因此 "${PORT}" 既可以解码为 int，也可以解码为 string
+type scalar string
+
+func (s scalar) MarshalYAML() (interface{}, error) {
+	return &yaml.Node{Kind: yaml.ScalarNode, Value: string(s)}, nil
+}
+
+// plainValue 返回 v 的副本，其中的 scalar 和无法读取的 fileRef 都转换为普通的 string
+// 计算结果交给自定义 Reloader 和 Dump 的调用方之前使用，内部类型不会泄漏到包外
+func plainValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case scalar:
+		return string(x)
+	case fileRef:
+		return string(x)
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i, vv := range x {
+			out[i] = plainValue(vv)
+		}
+		return out
+	}
+	if m, ok := toStringMap(v); ok {
+		out := make(map[string]interface{}, len(m))
+		for k, vv := range m {
+			out[k] = plainValue(vv)
+		}
+		return out
+	}
+	return v
+}
+
+// cutReference 从以 "${" 开头的 s 中截取引用表达式，支持嵌套的 ${...}
+func cutReference(s string) (expr, rest string, ok bool) {
+	if !strings.HasPrefix(s, "${") {
+		return "", s, false
+	}
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "${"):
+			depth++
+			i++
+		case s[i] == '}':
+			depth--
+			if depth == 0 {
+				return s[2:i], s[i+1:], true
+			}
+		}
+	}
+	return "", s, false
+}
+
+// evaluate 计算单个引用表达式 NAME、NAME:-fallback 或 NAME:?message
+func (in *interpolator) evaluate(expr string) (interface{}, error) {
+	name, op, arg := expr, "", ""
+	if i := strings.Index(expr, ":-"); i >= 0 {
+		name, op, arg = expr[:i], ":-", expr[i+2:]
+	} else if i := strings.Index(expr, ":?"); i >= 0 {
+		name, op, arg = expr[:i], ":?", expr[i+2:]
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("empty reference ${" + expr + "}")
+	}
+
+	var (
+		v     interface{}
+		found bool
+	)
+	if strings.Contains(name, ".") {
+		path := strings.Split(name, ".")
+		if _, _, _, ok := lookupPath(in.root, path); ok {
+			resolved, err := in.resolvePath(path)
+			if err != nil {
+				return nil, err
+			}
+			v, found = resolved, resolved != nil && fmt.Sprint(resolved) != ""
+		} else if op == "" {
+			return nil, fmt.Errorf("key %s not found", name)
+		}
+	} else {
+		env, ok := in.lookupEnv(name)
+		v, found = env, ok && env != ""
+	}
+
+	if found {
+		if str, ok := v.(string); ok {
+			return scalar(str), nil
+		}
+		return v, nil
+	}
+	switch op {
+	case ":-":
+		fallback, err := in.expand(arg)
+		if str, ok := fallback.(string); ok {
+			return scalar(str), err
+		}
+		return fallback, err
+	case ":?":
+		if arg == "" {
+			arg = "is required"
+		}
+		return nil, fmt.Errorf("%s: %s", name, arg)
+	}
+	return v, nil
+}
+
+// lookupPath 在配置树中查找 path 对应的值，同时返回其父节点和最后一段 key
+func lookupPath(root map[string]interface{}, path []string) (parent interface{}, last string, v interface{}, ok bool) {
+	var node interface{} = root
+	for _, seg := range path {
+		parent, last = node, seg
+		switch n := node.(type) {
+		case map[string]interface{}:
+			if node, ok = n[seg]; !ok {
+				return nil, "", nil, false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(seg)
+			if err != nil || i < 0 || i >= len(n) {
+				return nil, "", nil, false
+			}
+			node = n[i]
+		default:
+			return nil, "", nil, false
+		}
+	}
+	return parent, last, node, true
+}
+
+func setChild(parent interface{}, key string, v interface{}) {
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		p[key] = v
+	case []interface{}:
+		if i, err := strconv.Atoi(key); err == nil {
+			p[i] = v
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func envMap(m map[string]string) func(string) (string, bool) {
+	return func(k string) (string, bool) {
+		v, ok := m[k]
+		return v, ok
+	}
+}
+
+func TestInterpolateTree(t *testing.T) {
+	tree := parseTree(t, `
+server:
+  url: http://localhost:8080
+  port: 8080
+  callback: ${server.url}/callback
+  copyPort: ${server.port}
+mysql:
+  default:
+    dsn: ${MYSQL_DSN}
+    user: ${MYSQL_USER:-root}
+    empty: ${EMPTY:-fallback}
+    unset: prefix-${UNSET}-suffix
+    nested: ${UNSET:-${server.url}}
+    escaped: $${NOT_EXPANDED}
+list:
+  - ${server.port}
+  - ${list.0}
+`)
+	errs := interpolateTree(tree, envMap(map[string]string{
+		"MYSQL_DSN": "user:pass@tcp(db:3306)/app",
+		"EMPTY":     "",
+	}))
+	if len(errs) != 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+
+	srv := tree["server"].(map[string]interface{})
+	if srv["callback"] != scalar("http://localhost:8080/callback") {
+		t.Errorf("Unexpected callback: %#v", srv["callback"])
+	}
+	if srv["copyPort"] != 8080 {
+		t.Errorf("Expected typed reference, got %#v", srv["copyPort"])
+	}
+
+	def := tree["mysql"].(map[string]interface{})["default"].(map[string]interface{})
+	want := map[string]interface{}{
+		"dsn":     scalar("user:pass@tcp(db:3306)/app"),
+		"user":    scalar("root"),
+		"empty":   scalar("fallback"),
+		"unset":   scalar("prefix--suffix"),
+		"nested":  scalar("http://localhost:8080"),
+		"escaped": scalar("${NOT_EXPANDED}"),
+	}
+	for k, v := range want {
+		if def[k] != v {
+			t.Errorf("mysql.default.%s = %#v, want %#v", k, def[k], v)
+		}
+	}
+
+	list := tree["list"].([]interface{})
+	if list[0] != 8080 || list[1] != 8080 {
+		t.Errorf("Unexpected list: %#v", list)
+	}
+}
+
+func TestInterpolateErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{"required", "a:\n  b: ${SECRET:?secret must be set}\n", "SECRET: secret must be set"},
+		{"requiredDefault", "a:\n  b: ${SECRET:?}\n", "SECRET: is required"},
+		{"missingKey", "a:\n  b: ${a.missing}\n", "key a.missing not found"},
+		{"cycle", "a:\n  b: ${a.c}\n  c: ${a.b}\n", "reference cycle detected"},
+		{"selfCycle", "a:\n  b: x${a.b}\n", "a.b -> a.b"},
+		{"unterminated", "a:\n  b: ${OPEN\n", "unterminated reference"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := interpolateTree(parseTree(t, tt.yaml), envMap(nil))
+			if len(errs) == 0 {
+				t.Fatal("Expected error")
+			}
+			if !strings.Contains(errs[0].Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, errs[0])
+			}
+			if le, ok := errs[0].(*LoadError); !ok || le.Section != "a" {
+				t.Errorf("Expected *LoadError for section a, got %#v", errs[0])
+			}
+		})
+	}
+}
+
+func TestInterpolateLoader(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml": "config: dev\n",
+		"dev.yaml": `
+server:
+  name: ${APP_NAME:-default-app}
+  port: ${APP_PORT:-8080}
+  url: http://localhost:${server.port}
+`,
+	})
+	t.Setenv("APP_PORT", "9090")
+
+	l := New(WithConfigPath(filepath.Join(dir, "app.yaml")))
+	srv := RegisterTo(l, &server{})
+	if err := l.Err(); err != nil {
+		t.Fatalf("Unexpected load error: %v", err)
+	}
+	if srv.Name != "default-app" || srv.Port != 9090 || srv.Url != "http://localhost:9090" {
+		t.Errorf("Unexpected server: %+v", srv)
+	}
+
+	// UpdateConfig 同样展开，且引用的是更新后的配置
+	if err := l.UpdateConfig([]byte("server:\n  port: 7070\n"), "merge"); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	if srv.Port != 7070 || srv.Url != "http://localhost:7070" {
+		t.Errorf("Unexpected server after update: %+v", srv)
+	}
+
+	// 展开失败时拒绝更新
+	err := l.UpdateConfig([]byte("server:\n  port: 6060\n  name: ${MISSING_NAME:?name required}\n"), "merge")
+	if err == nil || !strings.Contains(err.Error(), "name required") {
+		t.Fatalf("Expected interpolation error, got %v", err)
+	}
+	if srv.Port != 7070 {
+		t.Errorf("Expected failed update to be rejected, got port %d", srv.Port)
+	}
+	if err := l.UpdateConfig([]byte("server:\n  port: 5050\n"), "merge"); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	if srv.Name != "default-app" {
+		t.Errorf("Expected rejected update to leave no trace, got name %q", srv.Name)
+	}
+}
+
+func TestInterpolatePlainStrings(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml": "logConfig:\n  level: ${LOG_LEVEL:-DEBUG}\n  handlers:\n    - ${LOG_LEVEL:-DEBUG}\n",
+	})
+	l := New(WithConfigPath(filepath.Join(dir, "app.yaml")))
+	logCfg := &customLog{}
+	l.Load(logCfg)
+
+	// 自定义 Reloader 和 Dump 得到的展开结果是普通的 string
+	if logCfg.Level != "DEBUG" {
+		t.Errorf("Expected custom Reloader to get a string, got %+v", logCfg)
+	}
+	dump := l.Dump()["logConfig"].(map[string]interface{})
+	if _, ok := dump["level"].(string); !ok {
+		t.Errorf("Expected Dump to return a string, got %#v", dump["level"])
+	}
+	if _, ok := dump["handlers"].([]interface{})[0].(string); !ok {
+		t.Errorf("Expected Dump to return strings in lists, got %#v", dump["handlers"])
+	}
+}
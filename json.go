@@ -0,0 +1,100 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"gopkg.in/yaml.v3"
+)
+
+// isJSON 判断配置内容是否按 JSON 解析：扩展名为 .json，或者内容以 { 开头且是合法的 JSON
+// yaml 的 flow mapping（例如 {a: 1}）同样以 { 开头，但不是合法的 JSON，仍然按 yaml 解析
+func isJSON(path string, b []byte) bool {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return true
+	}
+	b = bytes.TrimLeft(bytes.TrimPrefix(b, []byte("\xef\xbb\xbf")), " \t\r\n")
+	return len(b) > 0 && b[0] == '{' && json.Valid(b)
+}
+
+// parseJSON 将 JSON 解析为 yaml 文档节点，之后与 yaml 文件走相同的解密、解码和行号记录流程
+// JSON 是 yaml 的子集，先用 encoding/json 校验语法，再改写 yaml 不支持的转义后交给 yaml 解析
+// 重复的 key 按 JSON 的语义后面的生效；空文件与空的 yaml 文件一样视为空配置
+func parseJSON(b []byte) (*yaml.Node, error) {
+	b = bytes.TrimPrefix(b, []byte("\xef\xbb\xbf"))
+	if len(bytes.TrimSpace(b)) == 0 {
+		return &yaml.Node{}, nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		var syntax *json.SyntaxError
+		if errors.As(err, &syntax) {
+			return nil, fmt.Errorf("json: line %d: %v", 1+bytes.Count(b[:min(int(syntax.Offset), len(b))], []byte("\n")), err)
+		}
+		return nil, fmt.Errorf("json: %w", err)
+	}
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(rewriteEscapes(b), doc); err != nil {
+		return nil, fmt.Errorf("json: %w", err)
+	}
+	dropDuplicateKeys(doc)
+	return doc, nil
+}
+
+// rewriteEscapes 改写 yaml 不支持的 JSON 转义：\/ 改为 /，UTF-16 代理对（例如 \ud83d\ude00）合并为 \U0001F600
+func rewriteEscapes(b []byte) []byte {
+	if !bytes.Contains(b, []byte(`\`)) {
+		return b
+	}
+	out := make([]byte, 0, len(b))
+	for i := 0; i < len(b); i++ {
+		if b[i] != '\\' || i+1 == len(b) {
+			out = append(out, b[i])
+			continue
+		}
+		switch {
+		case b[i+1] == '/':
+			out = append(out, '/')
+		case b[i+1] == 'u' && i+12 <= len(b) && b[i+6] == '\\' && b[i+7] == 'u':
+			hi, err1 := strconv.ParseUint(string(b[i+2:i+6]), 16, 16)
+			lo, err2 := strconv.ParseUint(string(b[i+8:i+12]), 16, 16)
+			if err1 == nil && err2 == nil && utf16.IsSurrogate(rune(hi)) {
+				out = fmt.Appendf(out, `\U%08X`, utf16.DecodeRune(rune(hi), rune(lo)))
+				i += 11
+				continue
+			}
+			out = append(out, b[i], b[i+1])
+		default:
+			out = append(out, b[i], b[i+1])
+		}
+		i++
+	}
+	return out
+}
+
+// dropDuplicateKeys 删除 node 中每个 mapping 里重复的 key，只保留最后一个
+func dropDuplicateKeys(node *yaml.Node) {
+	for _, n := range node.Content {
+		dropDuplicateKeys(n)
+	}
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	last := make(map[string]int, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		last[node.Content[i].Value] = i
+	}
+	content := node.Content[:0]
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if last[node.Content[i].Value] == i {
+			content = append(content, node.Content[i], node.Content[i+1])
+		}
+	}
+	node.Content = content
+}
@@ -0,0 +1,187 @@
+package config
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseJSON(t *testing.T) {
+	content := "\xef\xbb\xbf{\n\t\"server\": {\n\t\t\"url\": \"http:\\/\\/example.com\\u00e9\",\n\t\t\"port\": 8080,\n\t\t\"port\": 9090\n\t},\n\t\"ratio\": 1e3,\n\t\"tags\": [\"a\", true, null]\n}\n"
+	doc, err := parseJSON([]byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := config{}
+	lines := map[string]int{}
+	if err := decodeDocument(doc, &tree, lines); err != nil {
+		t.Fatal(err)
+	}
+	srv, _ := toStringMap(tree["server"])
+	if srv["url"] != "http://example.comé" || srv["port"] != 9090 || tree["ratio"] != 1000.0 {
+		t.Errorf("Unexpected tree %v", tree)
+	}
+	if tags := tree["tags"].([]interface{}); tags[1] != true || tags[2] != nil {
+		t.Errorf("Unexpected tags %v", tags)
+	}
+	// 重复的 key 后面的生效，行号也指向后面的
+	if lines["server.url"] != 3 || lines["server.port"] != 5 || lines["tags"] != 8 {
+		t.Errorf("Unexpected lines %v", lines)
+	}
+
+	// yaml 不支持的转义：\/、代理对；转义的反斜杠后面的 / 保持不变
+	doc, err = parseJSON([]byte(`{"path": "a\\/b\/c", "emoji": "\ud83d\ude00\u00e9"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree = config{}
+	if err := decodeDocument(doc, &tree, map[string]int{}); err != nil {
+		t.Fatal(err)
+	}
+	if tree["path"] != `a\/b/c` || tree["emoji"] != "😀é" {
+		t.Errorf("Unexpected escapes %q", tree)
+	}
+
+	for _, tt := range []struct{ content, want string }{
+		{"{\n  \"a\": 1,\n  \"b\": }\n", "line 3"},
+		{"{\"a\": 1", "unexpected end"},
+		{"{\"a\": 1} {\"b\": 2}", "after top-level value"},
+	} {
+		if _, err := parseJSON([]byte(tt.content)); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Expected error containing %q for %q, got %v", tt.want, tt.content, err)
+		}
+	}
+}
+
+func TestEmptyJSONFile(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml":   "config: empty.json,blank\nserver:\n  port: 8080\n",
+		"empty.json": "",
+		"blank.json": "\xef\xbb\xbf \n",
+	})
+	l := New(WithConfigPath(filepath.Join(dir, "app.yaml")))
+	srv := RegisterTo(l, &server{})
+	if err := l.Err(); err != nil || srv.Port != 8080 {
+		t.Errorf("Expected empty JSON files to load like empty yaml, got %+v, %v", srv, err)
+	}
+}
+
+func TestIsJSON(t *testing.T) {
+	tests := []struct {
+		path, content string
+		want          bool
+	}{
+		{"a.json", "server: {}\n", true},
+		{"a.JSON", "", true},
+		{"a.yml", "  {\"server\": {\"port\": 1}}\n", true},
+		{"a.yml", "{server: {port: 1}}\n", false}, // yaml flow mapping
+		{"a.yml", "server:\n  port: 1\n", false},
+	}
+	for _, tt := range tests {
+		if got := isJSON(tt.path, []byte(tt.content)); got != tt.want {
+			t.Errorf("isJSON(%q, %q) = %v", tt.path, tt.content, got)
+		}
+	}
+}
+
+func TestJSONChain(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml":    "config: common,gen,extra.json\nserver:\n  name: app\n",
+		"common.yaml": "server:\n  port: 8080\n  allowOrigins: [a, b]\nredis:\n  default:\n    db: 1\n",
+		"gen.json":    "{\n  \"server\": {\n    \"allowOrigins\": [\"c\"],\n    \"url\": \"http:\\/\\/gen\"\n  }\n}\n",
+		"extra.json":  "{\"redis\": {\"default\": {\"password\": \"pw\"}}}",
+	})
+	l := New(WithConfigPath(filepath.Join(dir, "app.yaml")))
+	srv := RegisterTo(l, &server{})
+	rds := RegisterMapTo[*redis](l, "redis")
+	if err := l.Err(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// map 递归合并，数组整体覆盖
+	if srv.Name != "app" || srv.Port != 8080 || srv.Url != "http://gen" || len(srv.AllowOrigins) != 1 {
+		t.Errorf("Unexpected server %+v", srv)
+	}
+	if r := rds.Default(); r.DB != 1 || r.Password != "pw" {
+		t.Errorf("Unexpected redis %+v", r)
+	}
+	chain := l.Explain("server.url")
+	if len(chain) != 1 || chain[0].Source != filepath.Join(dir, "gen.json") || chain[0].Line != 4 {
+		t.Errorf("Unexpected origin %v", chain)
+	}
+}
+
+func TestResolveFileJSON(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"a.json": "{}",
+		"b.json": "{}",
+		"b.yaml": "",
+	})
+	if got := resolveFile(dir, "a"); got != filepath.Join(dir, "a.json") {
+		t.Errorf("Expected a.json, got %s", got)
+	}
+	if got := resolveFile(dir, "b"); got != filepath.Join(dir, "b.yaml") {
+		t.Errorf("Expected yaml to take precedence, got %s", got)
+	}
+	if got := resolveFile(dir, "c"); got != filepath.Join(dir, "c.yml") {
+		t.Errorf("Expected c.yml for missing file, got %s", got)
+	}
+}
+
+func TestUpdateConfigJSON(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml": "server:\n  name: app\n  port: 8080\n",
+	})
+	l := New(WithConfigPath(filepath.Join(dir, "app.yaml")))
+	srv := RegisterTo(l, &server{})
+
+	if err := l.UpdateConfig([]byte("{\"server\": {\"port\": 9090, \"url\": \"http:\\/\\/remote\"}}"), "merge"); err != nil {
+		t.Fatal(err)
+	}
+	if srv.Name != "app" || srv.Port != 9090 || srv.Url != "http://remote" {
+		t.Errorf("Unexpected server %+v", srv)
+	}
+	if err := l.UpdateConfig([]byte("{\"server\": {\"port\": 9091}"), "merge"); err == nil {
+		t.Error("Expected error for truncated JSON")
+	}
+}
+
+func TestWatchFilesJSON(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml": "config: gen\nserver:\n  port: 8080\n",
+	})
+	l := New(WithConfigPath(filepath.Join(dir, "app.yaml")))
+	srv := RegisterTo(l, &server{})
+	ch, cancel := l.Watch("server")
+	defer cancel()
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	l.WatchFiles(ctx, 10*time.Millisecond)
+
+	// 链中的文件之后以 .json 出现
+	saveAtomic(t, filepath.Join(dir, "gen.json"), "{\"server\": {\"port\": 9090}}")
+	waitChange(t, ch)
+	if srv.Port != 9090 {
+		t.Errorf("Expected port from gen.json, got %d", srv.Port)
+	}
+}
+
+func TestSOPSJSON(t *testing.T) {
+	// testdata/sops/prod.json 由 sops encrypt 加密 JSON 文件生成
+	useAgeKey(t)
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml":  "config: prod\n",
+		"prod.json": readFixture(t, "prod.json"),
+	})
+	l := New(WithConfigPath(filepath.Join(dir, "app.yaml")))
+	srv := RegisterTo(l, &server{})
+	rds := RegisterMapTo[*redis](l, "redis")
+	if err := l.Err(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if r := rds.Default(); r.Password != "hunter2" || r.DB != 3 || r.Addrs[0] != "a:1" || srv.Port != 9090 {
+		t.Errorf("Unexpected redis %+v, server %+v", r, srv)
+	}
+}
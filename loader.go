@@ -2,10 +2,16 @@ package config
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
 	"reflect"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"unicode"
@@ -18,12 +24,116 @@ type Section interface {
 }
 
 // Reloader 是一个可选接口，用于自定义 reload 行为
+// data 是 section 实际使用的配置子树，${...} 展开、secret 文件和解密得到的值都是普通的 string
 type Reloader interface {
 	Reload(data interface{})
 }
 
 type config map[string]interface{}
 
+// Loader 持有一份独立的配置：配置数据、section 注册表、读写锁以及配置来源
+// 同一进程中可以创建多个互不干扰的 Loader，包级函数使用默认 Loader
+type Loader struct {
+	data       config     // 文件和 UpdateConfig 分层合并后的原始配置
+	effective  config     // 叠加环境变量后 section 实际使用的配置，计算后不再修改
+	origins    provenance // data 中每个叶子配置项的覆盖链
+	stack      stack      // 按优先级排列的所有来源的内容，合并后得到 data
+	registry   []Section
+	mu         sync.RWMutex
+	once       sync.Once
+	configPath string
+	files      []string
+	envPrefix  string
+	keep       []string // overwrite 模式的更新需要保留的顶层 key，见 WithKeepOnOverwrite
+
+	secretKey     []byte // 解密 ENC[...] 值的密钥，见 WithSecretKey
+	secretKeyFile string
+	secretFiles   []string        // effective 中 file:// 引用的文件，供 WatchFiles 使用
+	sensitive     map[string]bool // 值来自 ENC[...]、secret 文件或 SOPS 加密文件的配置路径，只增不减，见 isSecret
+
+	history     []Revision
+	historySize int
+	version     int
+
+	errMu sync.Mutex
+	errs  []*LoadError
+
+	subMu sync.Mutex
+	subs  []*subscription
+
+	notifyMu  sync.Mutex
+	queue     [][]sectionChange // 已提交、等待通知的变化，按提交顺序排列
+	notifying bool              // 是否有 goroutine 正在投递 queue 中的通知
+}
+
+// Option 用于在 New 时定制 Loader
+type Option func(*Loader)
+
+// WithConfigPath 指定入口配置文件路径，优先于环境变量 CONFIG_PATH
+func WithConfigPath(path string) Option {
+	return func(l *Loader) {
+		l.configPath = path
+	}
+}
+
+// WithFiles 指定额外加载的配置文件（不含扩展名），优先于环境变量 config 和入口文件中的 config 字段
+func WithFiles(files ...string) Option {
+	return func(l *Loader) {
+		l.files = files
+	}
+}
+
+// WithEnvPrefix 启用环境变量覆盖，优先于环境变量 CONFIG_ENV_PREFIX
+// 例如 prefix 为 "APP" 时，APP_SERVER__PORT=9090 覆盖 server.port，
+// APP_REDIS__DEFAULT__ADDRS=a:6379,b:6379 覆盖 redis.default.addrs
+func WithEnvPrefix(prefix string) Option {
+	return func(l *Loader) {
+		l.envPrefix = prefix
+	}
+}
+
+// defaultKeeps 是 overwrite 模式默认保留的顶层 key，即 sections 中远程配置的连接信息
+var defaultKeeps = []string{"nacos", "etcd", "consul", "httpConfig"}
+
+// WithKeepOnOverwrite 追加 overwrite 模式的更新需要保留的顶层 key，默认保留 nacos、etcd、consul 和 httpConfig
+// 自定义远程配置的连接信息应该保留，否则远程配置会覆盖掉自己的连接信息
+func WithKeepOnOverwrite(keys ...string) Option {
+	return func(l *Loader) {
+		for _, key := range keys {
+			if !slices.Contains(l.keep, key) {
+				l.keep = append(l.keep, key)
+			}
+		}
+	}
+}
+
+// New 创建一个新的 Loader，配置栈中默认包含优先级为 PriorityFiles 的配置文件链
+// 未通过 Option 指定来源时，与包级函数一样读取环境变量 CONFIG_PATH、config 和 CONFIG_ENV_PREFIX
+func New(opts ...Option) *Loader {
+	chain := &fileChain{}
+	l := &Loader{
+		data: config{},
+		keep: slices.Clone(defaultKeeps),
+		stack: stack{
+			layers:  []layer{{name: filesSource, priority: PriorityFiles, source: chain}},
+			updates: layer{name: "update"},
+		},
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	chain.configPath, chain.files = l.configPath, l.files
+	return l
+}
+
+// std 是包级函数使用的默认 Loader
+var std = New()
+
+// Default 返回包级函数使用的默认 Loader
+func Default() *Loader {
+	return std
+}
+
 // autoSection 是一个自动推断名称的 Section 包装器
 type autoSection[T any] struct {
 	ptr  *T
@@ -35,17 +145,31 @@ func (a *autoSection[T]) SectionName() string {
 }
 
 func (a *autoSection[T]) Reload(data interface{}) {
-	if data == nil {
-		return
+	if err := reloadBinding(a, data); err != nil {
+		log.Printf("reload section %s error: %v\n", a.name, err)
 	}
-	b, err := yaml.Marshal(data)
-	if err != nil {
-		log.Printf("marshal section %s error: %v\n", a.name, err)
-		return
-	}
-	if err := yaml.Unmarshal(b, a.ptr); err != nil {
-		log.Printf("unmarshal section %s error: %v\n", a.name, err)
+}
+
+// prepare 将配置解码到带默认值的新值中并校验，被删除的 key 会恢复为默认值
+func (a *autoSection[T]) prepare(data interface{}) (interface{}, error) {
+	fresh := new(T)
+	if err := decodeSection(data, fresh); err != nil {
+		return nil, err
 	}
+	return fresh, validateValue(fresh)
+}
+
+func (a *autoSection[T]) commit(v interface{}) {
+	*a.ptr = *v.(*T)
+}
+
+func (a *autoSection[T]) valueType() reflect.Type {
+	return reflect.TypeOf(a.ptr).Elem()
+}
+
+func (a *autoSection[T]) owns(target interface{}) bool {
+	p, ok := target.(*T)
+	return ok && p == a.ptr
 }
 
 // lcFirst 将首字母转为小写
@@ -62,39 +186,22 @@ func lcFirst(s string) string {
 // 用法: var Server = config.Register(&server{})
 // 结构体名称 "server" 会自动作为 YAML 的 section 名称
 func Register[T any](ptr *T) *T {
+	return RegisterTo(std, ptr)
+}
+
+// RegisterTo 与 Register 相同，但注册到指定的 Loader
+// 用法: var Server = config.RegisterTo(l, &server{})
+func RegisterTo[T any](l *Loader, ptr *T) *T {
 	t := reflect.TypeOf(ptr).Elem()
 	name := lcFirst(t.Name())
 
 	wrapper := &autoSection[T]{ptr: ptr, name: name}
 
-	mu.Lock()
-	registry = append(registry, wrapper)
-	mu.Unlock()
-
-	once.Do(LoadConfig)
-
-	mu.RLock()
-	defer mu.RUnlock()
-	reloadAutoSection(wrapper)
+	l.register(wrapper)
 
 	return ptr
 }
 
-func reloadAutoSection[T any](a *autoSection[T]) {
-	s := loader.get(a.name)
-	if s == nil {
-		return
-	}
-	data, err := yaml.Marshal(s)
-	if err != nil {
-		log.Printf("marshal section %s error: %v\n", a.name, err)
-		return
-	}
-	if err := yaml.Unmarshal(data, a.ptr); err != nil {
-		log.Printf("unmarshal section %s error: %v\n", a.name, err)
-	}
-}
-
 // SectionMap 是一个通用的泛型配置 Map，内置了 Default() 方法
 // V 是具体的配置结构体指针，例如 *mongo
 type SectionMap[V any] map[string]V
@@ -115,217 +222,558 @@ func (a *autoMapSection[V]) SectionName() string {
 }
 
 func (a *autoMapSection[V]) Reload(data interface{}) {
-	if data == nil {
-		return
+	if err := reloadBinding(a, data); err != nil {
+		log.Printf("reload section %s error: %v\n", a.name, err)
 	}
-	b, err := yaml.Marshal(data)
+}
+
+// prepare 为每个 key 解码出带默认值的新值并校验
+func (a *autoMapSection[V]) prepare(data interface{}) (interface{}, error) {
+	fresh, err := decodeSectionMap[V](data)
 	if err != nil {
-		log.Printf("marshal section %s error: %v\n", a.name, err)
-		return
+		return nil, err
 	}
-	if err := yaml.Unmarshal(b, a.ptr); err != nil {
-		log.Printf("unmarshal section %s error: %v\n", a.name, err)
+	return fresh, validateValue(map[string]V(fresh))
+}
+
+// commit 原地替换 map 的内容，map 本身保持不变，调用方持有的 SectionMap 依然有效
+func (a *autoMapSection[V]) commit(v interface{}) {
+	m := *a.ptr
+	for k := range m {
+		delete(m, k)
+	}
+	for k, val := range v.(SectionMap[V]) {
+		m[k] = val
 	}
 }
 
+// decodeSectionMap 将配置子树解码为新的 SectionMap，每个值都先设置默认值
+func decodeSectionMap[V any](data interface{}) (SectionMap[V], error) {
+	fresh := make(SectionMap[V])
+	if data == nil {
+		return fresh, nil
+	}
+	entries, ok := toStringMap(data)
+	if !ok {
+		// 交给 yaml 报告类型错误
+		return fresh, decodeInto(data, &fresh)
+	}
+	for k, sub := range entries {
+		var v V
+		rv := reflect.ValueOf(&v).Elem()
+		if rv.Kind() == reflect.Ptr {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		if err := decodeSection(sub, &v); err != nil {
+			return nil, fmt.Errorf("%s: %w", k, err)
+		}
+		fresh[k] = v
+	}
+	return fresh, nil
+}
+
+func (a *autoMapSection[V]) valueType() reflect.Type {
+	return reflect.TypeOf(a.ptr).Elem()
+}
+
+func (a *autoMapSection[V]) owns(target interface{}) bool {
+	m, ok := target.(SectionMap[V])
+	return ok && reflect.ValueOf(m).UnsafePointer() == reflect.ValueOf(*a.ptr).UnsafePointer()
+}
+
 // RegisterMap 使用泛型注册 map 类型的配置 section
 // 用法: var Mongo = config.RegisterMap[*mongo]("mongo")
 // 返回 config.SectionMap[*mongo] 类型，可以直接使用 ["key"] 或 .Default()
 func RegisterMap[V any](name string) SectionMap[V] {
+	return RegisterMapTo[V](std, name)
+}
+
+// RegisterMapTo 与 RegisterMap 相同，但注册到指定的 Loader
+// 用法: var Mongo = config.RegisterMapTo[*mongo](l, "mongo")
+func RegisterMapTo[V any](l *Loader, name string) SectionMap[V] {
 	m := make(SectionMap[V])
 	wrapper := &autoMapSection[V]{ptr: &m, name: name}
 
-	mu.Lock()
-	registry = append(registry, wrapper)
-	mu.Unlock()
+	l.register(wrapper)
 
-	once.Do(LoadConfig)
+	return m
+}
 
-	mu.RLock()
-	defer mu.RUnlock()
-	reloadAutoMapSection(wrapper)
+// ensureLoaded 保证配置文件只在首次注册时加载一次
+func (l *Loader) ensureLoaded() {
+	l.once.Do(func() {
+		l.LoadConfig()
+	})
+}
 
-	return m
+// UpdateConfig 更新配置数据，data 可以是 yaml 或 JSON 对象，按内容识别
+// mode: "merge" (默认) - 递归合并新配置到现有配置，数组会覆盖
+// mode: "overwrite" - 丢弃除远程配置连接信息（nacos 等，见 WithKeepOnOverwrite）以外的所有现有配置，完全使用新配置
+func UpdateConfig(data []byte, mode string) error {
+	return std.UpdateConfig(data, mode)
 }
 
-func reloadAutoMapSection[V any](a *autoMapSection[V]) {
-	s := loader.get(a.name)
-	if s == nil {
-		return
-	}
-	data, err := yaml.Marshal(s)
+// UpdateConfig 更新该 Loader 的配置数据，mode 的含义同包级函数 UpdateConfig，详见 Apply
+func (l *Loader) UpdateConfig(data []byte, mode string) error {
+	_, err := l.Apply(data, mode)
+	return err
+}
+
+// Report 描述一次成功应用的更新
+type Report struct {
+	Changed []string // 内容发生变化的 section 名称，按字母排序
+	Changes []Change // 原始配置树中变化的配置项，敏感项已脱敏，可用于审计日志
+}
+
+// Apply 与 UpdateConfig 相同，但额外返回本次更新的报告
+func Apply(data []byte, mode string) (*Report, error) {
+	return std.Apply(data, mode)
+}
+
+// Apply 以事务方式更新配置：先在配置树的副本上合并、展开，再为所有 section 解码并校验出全新的值，
+// 全部成功后才一次性替换配置树并提交所有 section
+// 任何一步失败都返回错误，配置树和所有 section 保持原样
+// 内容发生变化的 section 会在释放写锁之后通知 OnChange / Watch 的订阅者
+func (l *Loader) Apply(data []byte, mode string) (*Report, error) {
+	return l.ApplyFrom("update", data, mode)
+}
+
+// ApplyFrom 与 Apply 相同，但使用 source 描述更新的来源，例如 "nacos:app.yaml"
+// source 记录在历史版本和 Explain 的来源中，适合远程配置的集成
+func ApplyFrom(source string, data []byte, mode string) (*Report, error) {
+	return std.ApplyFrom(source, data, mode)
+}
+
+// ApplyFrom 与 Apply 相同，但使用 source 描述更新的来源，例如 "nacos:app.yaml"
+func (l *Loader) ApplyFrom(source string, data []byte, mode string) (*Report, error) {
+	sections, changes, err := l.update(source, data, mode)
+	l.notify()
 	if err != nil {
-		log.Printf("marshal section %s error: %v\n", a.name, err)
-		return
+		return nil, err
 	}
-	if err := yaml.Unmarshal(data, a.ptr); err != nil {
-		log.Printf("unmarshal section %s error: %v\n", a.name, err)
+	report := &Report{Changed: make([]string, 0, len(sections)), Changes: changes}
+	for _, c := range sections {
+		report.Changed = append(report.Changed, c.name)
 	}
+	sort.Strings(report.Changed)
+	return report, nil
 }
 
-var (
-	loader   = &config{}
-	once     sync.Once
-	registry []Section
-	mu       sync.RWMutex
-)
+// update 将 data 按 mode 应用到配置树，source 记录在历史版本和配置项的来源中
+func (l *Loader) update(source string, data []byte, mode string) ([]sectionChange, []Change, error) {
+	// 先完成首次加载，否则之后的懒加载会覆盖本次更新
+	l.ensureLoaded()
+	l.mu.Lock()
+	defer l.mu.Unlock()
 
-// UpdateConfig 更新配置数据
-// mode: "merge" (默认) - 递归合并新配置到现有配置，数组会覆盖
-// mode: "overwrite" - 丢弃除 nacos 以外的所有现有配置，完全使用新配置
-func UpdateConfig(data []byte, mode string) error {
-	mu.Lock()
-	defer mu.Unlock()
+	patch, lines, err := parseContent(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	origins := originsOf(patch, updateOrigin(source, lines))
 
+	next := l.stack
 	if mode == "overwrite" {
-		// 1. 备份 Nacos 配置 (防止断连)
-		var nacosBackup interface{}
-		if currentNacos := loader.get("nacos"); currentNacos != nil {
-			// 深拷贝或直接引用均可，因为我们要创建一个全新的 loader map
-			// 但为了安全，最好重新 marshal/unmarshal 或者假设 map 不会被修改
-			// 这里简单起见直接引用，因为下面我们创建了 newLoader
-			nacosBackup = currentNacos
-		}
+		// 丢弃之前的更新，各个来源只保留 nacos 等远程配置的连接信息 (防止断连)
+		// 新配置中的 nacos 会覆盖来源中的，这是预期的
+		next.updates.data, next.updates.origins = patch, origins
+		next.overwritten = true
+	} else {
+		// Default: Merge 模式，递归合并嵌套 map，数组整体覆盖
+		// 在副本上合并，失败时原来的配置树保持不变
+		merged := mergeTree(copyValue(next.updates.data).(map[string]interface{}), patch)
+		next.updates.data = merged
+		next.updates.origins = next.updates.origins.with(merged, origins)
+	}
+
+	return l.applyStack(source, next)
+}
+
+// applyStack 以事务方式将 next 设为新的配置栈，合并出原始配置树并提交所有 section，调用方必须持有写锁
+// 返回变化的 section 和新版本中原始配置项的变化，没有任何变化时不记录新版本；变化的 section 同时加入通知队列，调用方释放锁之后调用 notify
+// next 提交后成为历史版本的一部分，调用方之后不能再修改它
+func (l *Loader) applyStack(source string, next stack) ([]sectionChange, []Change, error) {
+	tree, origins := next.compose(l.keep)
+	l.markSensitiveOrigins(origins)
+	effective, files, errs := l.resolveTree(tree, origins)
+	if errs = l.keepBrokenRefs(tree, effective, errs); len(errs) > 0 {
+		return nil, nil, errors.Join(errs...)
+	}
+	pending, errs := l.prepare(effective)
+	if len(errs) > 0 {
+		return nil, nil, errors.Join(errs...)
+	}
 
-		// 2. 创建新 Loader (清空操作)
-		newLoader := &config{}
+	changes := diffSections(l.effective, effective)
+	var recorded []Change
+	// 原始配置和 section 实际使用的配置都没有变化时不记录新版本，也不通知，
+	// 例如重复的更新或远程来源重复的推送，它们不会把真正的变化挤出历史
+	if diff := diffTrees(l.data, tree); len(diff) > 0 || len(changes) > 0 {
+		recorded = l.record(source, diff, tree, next).Changes
+	}
+	l.data, l.effective, l.origins, l.stack = tree, effective, origins, next
+	l.secretFiles = files
+	l.commit(pending)
+	l.enqueue(changes)
+	return changes, recorded, nil
+}
+
+// Load 加载配置到指定的 section 结构体中
+func Load(section Section) {
+	std.Load(section)
+}
 
-		// 3. 恢复 Nacos 配置 (作为基底)
-		if nacosBackup != nil {
-			(*newLoader)["nacos"] = nacosBackup
+// Load 加载配置到指定的 section 结构体中，并注册到该 Loader
+func (l *Loader) Load(section Section) {
+	l.register(section)
+}
+
+// register 将 section 加入注册表，确保配置已加载后使用当前配置刷新它
+// 配置无法解码时 section 保持原样，校验失败时仍然使用新值，错误都记录到 Errors 中
+func (l *Loader) register(section Section) {
+	if _, ok := section.(binding); !ok {
+		if _, ok := section.(Reloader); !ok {
+			section = &plainSection{section}
 		}
+	}
 
-		// 4. 解析新配置 (新配置中的 nacos 会覆盖备份的，这是预期的)
-		if err := yaml.Unmarshal(data, newLoader); err != nil {
-			return err
+	l.mu.Lock()
+	l.registry = append(l.registry, section)
+	l.mu.Unlock()
+
+	l.ensureLoaded()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// 新注册的 section 带来了环境变量类型转换和 file:// 引用所需的类型信息
+	// 其他错误与类型无关，已经在 LoadConfig 中记录过，这里只补充该 section 的错误
+	effective, files, errs := l.resolveTree(l.data, l.origins)
+	l.effective, l.secretFiles = effective, files
+	for _, err := range errs {
+		if le, ok := err.(*LoadError); ok && le.Section == section.SectionName() {
+			l.addErrorOnce(err)
 		}
+	}
 
-		// 5. 替换全局 loader
-		loader = newLoader
-	} else {
-		// Default: Merge 模式
-		if err := yaml.Unmarshal(data, loader); err != nil {
-			return err
+	data := l.effective.get(section.SectionName())
+	switch r := section.(type) {
+	case binding:
+		if err := reloadBinding(r, data); err != nil {
+			l.addError(&LoadError{Section: section.SectionName(), Err: err})
+		}
+	case Reloader:
+		if data != nil {
+			r.Reload(plainValue(data))
 		}
 	}
+}
 
-	// 刷新所有已注册的 section
-	for _, section := range registry {
-		reloadSection(section)
+// resolveTree 根据原始配置树计算 section 实际使用的配置树，调用方必须持有锁
+// 原始配置树保存文件和 UpdateConfig 的分层结果，环境变量覆盖和 ${...} 展开只作用于计算结果，
+// 因此环境变量覆盖不会被 overwrite 模式的更新丢弃，展开也总是基于最新的配置
+// 展开之后读取 !file 和 secret 字段上 file:// 引用的文件，再解密 ENC[...] 加密值，文件内容和明文不会再被展开，也只出现在计算结果中
+// origins 是 data 的来源记录，file:// 只在值来自本地时读取，见 localPaths
+// 同时返回引用的 secret 文件，供 WatchFiles 监听；文件内容和解密得到的明文的路径记录为敏感，见 isSecret
+// 展开、读取或解密失败的值保持原样，错误由调用方决定如何处理
+func (l *Loader) resolveTree(data config, origins provenance) (config, []string, []error) {
+	tree := copyValue(data).(map[string]interface{})
+	var env map[string]string
+	if prefix := l.envPrefixOrEnv(); prefix != "" {
+		env = applyEnvOverlay(tree, prefix, os.Environ(), l.sectionTypes())
 	}
-	return nil
+	errs := interpolateTree(tree, os.LookupEnv)
+	files, ferrs := resolveFileRefs(tree, l.secretFieldPaths(), localPaths(origins, env), l.markSensitive)
+	errs = append(errs, ferrs...)
+	errs = append(errs, l.decryptTree(tree)...)
+	return tree, files, errs
 }
 
-// Load 加载配置到指定的 section 结构体中
-func Load(section Section) {
-	mu.Lock()
-	registry = append(registry, section)
-	mu.Unlock()
+// binding 是内置 section 包装器实现的内部接口，刷新分为两个阶段：
+// prepare 将配置解码到全新的值中并校验，不修改 section；commit 把准备好的值替换进 section
+// prepare 只有解码失败时才返回 nil 值，校验失败时同时返回新值和错误
+type binding interface {
+	prepare(data interface{}) (interface{}, error)
+	commit(v interface{})
+}
 
-	once.Do(LoadConfig)
+// reloadBinding 立即刷新 section：能解码就提交新值，再返回解码或校验错误
+func reloadBinding(b binding, data interface{}) error {
+	v, err := b.prepare(data)
+	if v != nil {
+		b.commit(v)
+	}
+	return err
+}
 
-	mu.RLock()
-	defer mu.RUnlock()
-	reloadSection(section)
+// pendingSection 是 prepare 阶段得到的、等待提交的 section 新值
+type pendingSection struct {
+	section Section
+	data    interface{} // section 使用的配置子树
+	value   interface{} // binding 准备好的新值，自定义 Reloader 为 nil
 }
 
-func reloadSection(section Section) {
-	s := loader.get(section.SectionName())
-	if s == nil {
-		return
+// prepare 使用 tree 为所有已注册的 section 准备新值，不修改任何 section
+// 任何 section 解码或校验失败都会返回错误，调用方应放弃整个更新
+func (l *Loader) prepare(tree config) ([]pendingSection, []error) {
+	pending := make([]pendingSection, 0, len(l.registry))
+	var errs []error
+	for _, section := range l.registry {
+		p := pendingSection{section: section, data: tree.get(section.SectionName())}
+		if b, ok := section.(binding); ok {
+			v, err := b.prepare(p.data)
+			if err != nil {
+				errs = append(errs, &LoadError{Section: section.SectionName(), Err: err})
+				continue
+			}
+			p.value = v
+		}
+		pending = append(pending, p)
 	}
-	// 优先使用 Reloader 接口
-	if r, ok := section.(Reloader); ok {
-		r.Reload(s)
-		return
+	return pending, errs
+}
+
+// commit 提交 prepare 得到的所有新值
+// 自定义的 Reloader 自行解码，无法预先校验，只在这里调用
+func (l *Loader) commit(pending []pendingSection) {
+	for _, p := range pending {
+		switch r := p.section.(type) {
+		case binding:
+			r.commit(p.value)
+		case Reloader:
+			if p.data != nil {
+				r.Reload(plainValue(p.data))
+			}
+		}
 	}
-	// 降级到原始方式
-	data, err := yaml.Marshal(s)
-	if err != nil {
-		log.Printf("marshal section %s error: %v\n", section.SectionName(), err)
-		return
+}
+
+// plainSection 包装通过 Load 注册、没有实现 Reloader 的普通 section
+// 结构体指针会解码到带默认值的新值中再整体替换，其余类型直接解码
+type plainSection struct {
+	Section
+}
+
+func (p *plainSection) prepare(data interface{}) (interface{}, error) {
+	v := reflect.ValueOf(p.Section)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return data, nil
+	}
+	fresh := reflect.New(v.Elem().Type())
+	if err := decodeSection(data, fresh.Interface()); err != nil {
+		return nil, err
 	}
-	if err := yaml.Unmarshal(data, section); err != nil {
-		log.Printf("unmarshal section %s error: %v\n", section.SectionName(), err)
+	return fresh.Interface(), validateValue(fresh.Interface())
+}
+
+func (p *plainSection) commit(v interface{}) {
+	rv := reflect.ValueOf(p.Section)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		if err := decodeInto(v, p.Section); err != nil {
+			log.Printf("reload section %s error: %v\n", p.SectionName(), err)
+		}
+		return
 	}
+	rv.Elem().Set(reflect.ValueOf(v).Elem())
+}
+
+func (p *plainSection) valueType() reflect.Type {
+	return reflect.TypeOf(p.Section)
+}
+
+func (p *plainSection) owns(target interface{}) bool {
+	return target == p.Section
 }
 
-func (c *config) get(key string) interface{} {
-	if c == nil || *c == nil {
+// decodeInto 将配置树中的数据解码到 out 中
+func decodeInto(data interface{}, out interface{}) error {
+	if data == nil {
 		return nil
 	}
-	return (*c)[key]
+	b, err := yaml.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(b, out)
 }
 
-func appendByte(buff *bytes.Buffer, b []byte) {
-	buff.Write(b)
-	if len(b) > 0 && b[len(b)-1] != '\n' {
-		buff.WriteByte('\n')
+func (c config) get(key string) interface{} {
+	if c == nil {
+		return nil
 	}
+	return c[key]
 }
 
-// LoadConfig 加载配置文件
-// 支持通过环境变量 CONFIG_PATH 指定配置文件路径，默认为 ./config/app.yml
-// 支持通过环境变量 config 指定额外加载的配置文件（逗号分隔）
-func LoadConfig() {
-	mu.Lock()
-	defer mu.Unlock()
-	configPath := os.Getenv("CONFIG_PATH")
-	if configPath == "" {
-		if _, err := os.Stat("./config/app.yml"); err == nil {
-			configPath = "./config/app.yml"
-		} else if _, err := os.Stat("./config/app.yaml"); err == nil {
-			configPath = "./config/app.yaml"
-		} else {
-			configPath = "./config/app.yml"
+// parseFile 独立解析单个配置文件，文件中的多个文档 (---) 按顺序递归合并
+// 每个文件拥有自己的解析上下文，锚点等不会泄漏到其他文件
+// 同时返回每个叶子配置项所在的行号，后面的文档覆盖前面的
+// .json 文件或内容是 JSON 的文件按 JSON 解析，合并方式与 yaml 相同
+// 带 sops 元数据的文件先用本地的 age 身份或 PGP 私钥解密并校验 MAC，见 decryptSOPS，同时返回解密的配置项路径
+// 返回的错误是带有文件路径的 *LoadError，yaml 错误本身带有行号
+func parseFile(path string) (config, map[string]int, map[string]bool, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, nil, &LoadError{File: path, Err: err}
+	}
+	docs, err := parseDocuments(path, b)
+	if err != nil {
+		return nil, nil, nil, &LoadError{File: path, Err: err}
+	}
+	// SOPS 加密的文件在内存中解密，明文不会写回磁盘
+	decrypted, err := decryptSOPS(docs)
+	if err != nil {
+		return nil, nil, nil, &LoadError{File: path, Err: err}
+	}
+
+	tree := config{}
+	lines := map[string]int{}
+	for _, node := range docs {
+		doc := config{}
+		if err := decodeDocument(node, &doc, lines); err != nil {
+			return nil, nil, nil, &LoadError{File: path, Err: err}
 		}
+		// 只有本地配置文件可以用 !file 引用文件
+		markFileTags(node, doc)
+		tree = mergeTree(tree, doc)
 	}
-	configDir := filepath.Dir(configPath)
+	return tree, lines, decrypted, nil
+}
 
-	buff := bytes.Buffer{}
-	env := os.Getenv("config")
+// Parse 将 yaml 或 JSON 内容解析为配置树，JSON 按内容识别，供远程来源实现 Source.Load
+// 多个 yaml 文档按顺序合并；ENC[...] 值与配置文件中的含义相同，在计算配置时解密
+// 远程内容不能引用本地文件：!file 标签返回错误，file:// 值保持原样
+func Parse(data []byte) (map[string]any, error) {
+	tree, _, err := parseContent(data)
+	return tree, err
+}
+
+// parseContent 解析 UpdateConfig 或远程来源的内容，同时返回每个叶子配置项所在的行号
+func parseContent(data []byte) (config, map[string]int, error) {
+	docs, err := parseDocuments("", data)
+	if err != nil {
+		return nil, nil, err
+	}
+	tree := config{}
+	lines := map[string]int{}
+	for _, node := range docs {
+		if err := rejectFileTags(node); err != nil {
+			return nil, nil, err
+		}
+		doc := config{}
+		if err := decodeDocument(node, &doc, lines); err != nil {
+			return nil, nil, err
+		}
+		tree = mergeTree(tree, doc)
+	}
+	return tree, lines, nil
+}
 
-	if env == "" {
-		app, err := os.ReadFile(configPath)
+// parseDocuments 将文件内容解析为 yaml 文档节点，JSON 文件（见 isJSON）只有一个文档
+func parseDocuments(path string, b []byte) ([]*yaml.Node, error) {
+	if isJSON(path, b) {
+		doc, err := parseJSON(b)
 		if err != nil {
-			log.Printf("app file error: %v\n", err)
-		} else {
-			appendByte(&buff, app)
-			if err := yaml.Unmarshal(app, &loader); err != nil {
-				log.Printf("unmarshal app.yml error: %v\n", err)
-			} else if c := loader.get("config"); c != nil {
-				if configVal, ok := c.(string); ok {
-					env = configVal
-				}
+			return nil, err
+		}
+		return []*yaml.Node{doc}, nil
+	}
+	var docs []*yaml.Node
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	for {
+		node := &yaml.Node{}
+		if err := dec.Decode(node); err != nil {
+			if errors.Is(err, io.EOF) {
+				return docs, nil
 			}
+			return nil, err
+		}
+		docs = append(docs, node)
+	}
+}
+
+// configExts 是配置文件支持的扩展名，按查找的优先级排列
+var configExts = []string{".yml", ".yaml", ".json"}
+
+// resolveFile 根据名称在配置目录中查找配置文件，依次尝试 .yml、.yaml、.json，都不存在时返回 .yml
+// 名称已经带有这些扩展名时直接使用，例如 config: common,generated.json
+func resolveFile(dir, name string) string {
+	if slices.Contains(configExts, strings.ToLower(filepath.Ext(name))) {
+		return filepath.Join(dir, name)
+	}
+	for _, ext := range configExts {
+		filePath := filepath.Join(dir, name+ext)
+		if _, err := os.Stat(filePath); err == nil {
+			return filePath
 		}
 	}
+	return filepath.Join(dir, name+configExts[0])
+}
+
+// candidateFiles 返回 resolveFile 可能使用的所有文件，供 WatchFiles 监听
+func candidateFiles(dir, name string) []string {
+	if slices.Contains(configExts, strings.ToLower(filepath.Ext(name))) {
+		return []string{filepath.Join(dir, name)}
+	}
+	files := make([]string, len(configExts))
+	for i, ext := range configExts {
+		files[i] = filepath.Join(dir, name+ext)
+	}
+	return files
+}
 
-	if env != "" {
-		for _, file := range strings.Split(env, ",") {
-			file = strings.TrimSpace(file)
-			if file == "" {
+// LoadConfig 加载配置文件
+// 支持通过环境变量 CONFIG_PATH 指定配置文件路径，默认依次查找 ./config/app.yml、app.yaml、app.json
+// 支持通过环境变量 config 指定额外加载的配置文件（逗号分隔）
+// 所有文件错误汇总为 *LoadError 返回，也可以之后通过 Err() 获取
+func LoadConfig() error {
+	return std.LoadConfig()
+}
+
+// LoadConfig 重新加载该 Loader 配置栈中的所有来源，默认只有配置文件链
+// 链中的每个文件独立解析，再按顺序递归合并，后面的文件覆盖前面的
+// 通过 WithConfigPath / WithFiles 指定的来源优先于环境变量
+// 加载失败的来源保持原来的内容，UpdateConfig 写入的内容保持不变
+// 首次加载之后的重新加载与 UpdateConfig 一样以事务方式应用：任何 section 校验失败时保持当前配置，成功时通知订阅者
+func (l *Loader) LoadConfig() error {
+	err := l.loadConfig()
+	l.notify()
+	return err
+}
+
+func (l *Loader) loadConfig() error {
+	ctx := context.Background()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var errs []error
+	next := l.stack.replace(0, l.stack.layers[0])
+	for i, ly := range next.layers {
+		loaded, err := ly.load(ctx)
+		if err != nil {
+			errs = append(errs, unwrapJoined(err)...)
+			// 首次加载使用能够解析的部分，重新加载时失败的来源保持原来的内容
+			if l.version > 0 {
 				continue
 			}
-			filePath := filepath.Join(configDir, file+".yml")
-			if _, err := os.Stat(filePath); err != nil {
-				filePathYaml := filepath.Join(configDir, file+".yaml")
-				if _, err := os.Stat(filePathYaml); err == nil {
-					filePath = filePathYaml
-				}
-			}
-			b, err := os.ReadFile(filePath)
-			if err != nil {
-				log.Printf("file %s error: %v\n", filePath, err)
-			} else {
-				appendByte(&buff, b)
-			}
 		}
+		next.layers[i] = loaded
 	}
 
-	if buff.Len() > 0 {
-		if err := yaml.Unmarshal(buff.Bytes(), &loader); err != nil {
-			log.Printf("unmarshal config error: %v\n", err)
+	if l.version > 0 {
+		if _, _, err := l.applyStack(next.label(), next); err != nil {
+			errs = append(errs, unwrapJoined(err)...)
 		}
+		l.setErrors(errs)
+		return errors.Join(errs...)
 	}
+
+	tree, origins := next.compose(l.keep)
+	// 先记录敏感的路径，历史中的变化才能脱敏
+	l.markSensitiveOrigins(origins)
+	effective, files, rerrs := l.resolveTree(tree, origins)
+	l.record(next.label(), diffTrees(l.data, tree), tree, next)
+	l.data, l.origins, l.stack = tree, origins, next
+	l.effective, l.secretFiles = effective, files
+	errs = append(errs, rerrs...)
+	l.setErrors(errs)
+	return errors.Join(errs...)
 }
@@ -1,9 +1,11 @@
 package config
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
-	"sync"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -55,11 +57,7 @@ type gateway struct {
 
 // resetForTest 重置全局状态，用于测试隔离
 func resetForTest() {
-	mu.Lock()
-	defer mu.Unlock()
-	loader = &config{}
-	registry = nil
-	once = sync.Once{}
+	std = New()
 }
 
 // TestConfigChain 测试配置链式加载 (app.yaml -> config: common,dev -> common.yaml + dev.yaml)
@@ -358,3 +356,251 @@ func TestEnvironmentVariable(t *testing.T) {
 
 	t.Log("✅ Environment variable test passed")
 }
+
+// TestLoaderIsolation 测试多个 Loader 实例之间互不影响
+func TestLoaderIsolation(t *testing.T) {
+	wd, _ := os.Getwd()
+	configPath := filepath.Join(wd, "config", "app.yaml")
+
+	a := New(WithConfigPath(configPath))
+	b := New(WithConfigPath(configPath), WithFiles("common"))
+
+	srvA := RegisterTo(a, &server{})
+	srvB := RegisterTo(b, &server{})
+	authB := RegisterTo(b, &auth{})
+
+	if srvA.Port != 8080 {
+		t.Errorf("Expected loader a server.Port = 8080, got %d", srvA.Port)
+	}
+	// b 只加载 common.yaml，没有 server section
+	if srvB.Port != 0 {
+		t.Errorf("Expected loader b server.Port = 0, got %d", srvB.Port)
+	}
+	if authB.JWTSecret == "" {
+		t.Error("Expected loader b auth.JWTSecret from common.yaml")
+	}
+
+	if err := a.UpdateConfig([]byte("server:\n  port: 9090\n"), "merge"); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	if srvA.Port != 9090 {
+		t.Errorf("Expected loader a server.Port = 9090, got %d", srvA.Port)
+	}
+	if srvB.Port != 0 {
+		t.Errorf("Expected loader b to be unaffected, got server.Port = %d", srvB.Port)
+	}
+
+	mongoA := RegisterMapTo[*mongo](a, "mongo")
+	if mongoA.Default() == nil {
+		t.Fatal("Expected loader a mongo['default'] to exist")
+	}
+
+	t.Log("✅ Loader isolation test passed")
+}
+
+// TestUpdateConfigDeepMerge 测试 merge 模式递归合并嵌套结构
+func TestUpdateConfigDeepMerge(t *testing.T) {
+	wd, _ := os.Getwd()
+	l := New(WithConfigPath(filepath.Join(wd, "config", "app.yaml")))
+
+	srv := RegisterTo(l, &server{})
+	redisMap := RegisterMapTo[*redis](l, "redis")
+
+	err := l.UpdateConfig([]byte(`
+server:
+  port: 9090
+redis:
+  default:
+    addrs:
+      - redis-a:6379
+      - redis-b:6379
+`), "merge")
+	if err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+
+	if srv.Port != 9090 {
+		t.Errorf("Expected server.Port = 9090, got %d", srv.Port)
+	}
+	// 同级的其他字段应保留
+	if srv.Url != "http://localhost:8080" {
+		t.Errorf("Expected server.Url to be kept, got '%s'", srv.Url)
+	}
+	if srv.Name != "unicorn-gateway" {
+		t.Errorf("Expected server.Name to be kept, got '%s'", srv.Name)
+	}
+
+	def := redisMap.Default()
+	if def == nil {
+		t.Fatal("Expected redis['default'] to exist")
+	}
+	// 数组整体覆盖
+	if !reflect.DeepEqual(def.Addrs, []string{"redis-a:6379", "redis-b:6379"}) {
+		t.Errorf("Expected redis.default.addrs to be replaced, got %v", def.Addrs)
+	}
+	if def.Password != "admin" || def.DB != 2 {
+		t.Errorf("Expected redis.default password/db to be kept, got %+v", def)
+	}
+	if redisMap["session"] == nil {
+		t.Error("Expected redis['session'] to be kept")
+	}
+
+	t.Log("✅ UpdateConfig deep merge test passed")
+}
+
+// writeConfigFiles 在临时目录中写入一组配置文件，返回目录路径
+func writeConfigFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s failed: %v", name, err)
+		}
+	}
+	return dir
+}
+
+// TestConfigChainPerFile 测试链中的文件独立解析后再合并
+func TestConfigChainPerFile(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml": "config: common,dev\n",
+		// common 和 dev 都定义了 server，拼接后解析会报重复 key
+		"common.yaml": `
+base: &base
+  name: common
+server:
+  name: common-app
+  port: 8000
+`,
+		// 多文档文件按顺序合并
+		"dev.yml": `
+server:
+  port: 8080
+---
+server:
+  url: http://dev
+`,
+	})
+
+	l := New(WithConfigPath(filepath.Join(dir, "app.yaml")))
+	srv := RegisterTo(l, &server{})
+
+	if srv.Name != "common-app" {
+		t.Errorf("Expected server.Name from common.yaml, got '%s'", srv.Name)
+	}
+	if srv.Port != 8080 {
+		t.Errorf("Expected server.Port = 8080 from dev.yml, got %d", srv.Port)
+	}
+	if srv.Url != "http://dev" {
+		t.Errorf("Expected server.Url from second document of dev.yml, got '%s'", srv.Url)
+	}
+
+	t.Log("✅ Config chain per-file test passed")
+}
+
+// TestParseFileError 测试解析错误带有文件路径和行号，且锚点不会跨文件
+func TestParseFileError(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"common.yaml": "base: &base\n  name: common\n",
+		"dev.yaml":    "server:\n  name: dev\nother: *base\n",
+		"bad.yaml":    "server:\n  name: dev\n  name: again\n",
+	})
+
+	if _, _, _, err := parseFile(filepath.Join(dir, "common.yaml")); err != nil {
+		t.Fatalf("parse common.yaml failed: %v", err)
+	}
+	_, _, _, err := parseFile(filepath.Join(dir, "dev.yaml"))
+	if err == nil {
+		t.Fatal("Expected error for alias defined in another file")
+	}
+	if !strings.Contains(err.Error(), "dev.yaml") {
+		t.Errorf("Expected error to mention dev.yaml, got: %s", err)
+	}
+
+	_, _, _, err = parseFile(filepath.Join(dir, "bad.yaml"))
+	if err == nil {
+		t.Fatal("Expected error for duplicate key")
+	}
+	if msg := err.Error(); !strings.Contains(msg, "bad.yaml") || !strings.Contains(msg, "line 3") {
+		t.Errorf("Expected error to mention bad.yaml and line 3, got: %s", msg)
+	}
+
+	t.Logf("✅ Parse file error test passed: %v", err)
+}
+
+// customLog 使用自定义 Reloader 的 section
+type customLog struct {
+	Level   string
+	reloads int
+}
+
+func (c *customLog) SectionName() string { return "logConfig" }
+
+func (c *customLog) Reload(data interface{}) {
+	c.reloads++
+	if m, ok := data.(map[string]interface{}); ok {
+		c.Level, _ = m["level"].(string)
+	}
+}
+
+// TestApplyTransactional 测试更新要么全部生效，要么完全不生效
+func TestApplyTransactional(t *testing.T) {
+	l := newTestLoader(t)
+	srv := RegisterTo(l, &server{})
+	mongoMap := RegisterMapTo[*mongo](l, "mongo")
+	atomicSrv := RegisterAtomicTo[server](l)
+	logCfg := &customLog{}
+	l.Load(logCfg)
+	reloads := logCfg.reloads
+
+	// server 解码失败，同一次更新中的 mongo 和 logConfig 也不能生效
+	report, err := l.Apply([]byte(`
+server:
+  port: not-a-number
+mongo:
+  default:
+    uri: mongodb://changed
+logConfig:
+  level: DEBUG
+`), "merge")
+	if err == nil || report != nil {
+		t.Fatalf("Expected error and no report, got %v %v", report, err)
+	}
+	var le *LoadError
+	if !errors.As(err, &le) || le.Section != "server" {
+		t.Errorf("Expected *LoadError for server, got %v", err)
+	}
+	if srv.Port != 8080 || atomicSrv.Get().Port != 8080 {
+		t.Errorf("Expected server untouched, got %+v %+v", srv, atomicSrv.Get())
+	}
+	if mongoMap.Default().URI != "mongodb://localhost:27017" {
+		t.Errorf("Expected mongo untouched, got %+v", mongoMap.Default())
+	}
+	if logCfg.reloads != reloads || logCfg.Level != "INFO" {
+		t.Errorf("Expected custom Reloader not to be called, got %+v", logCfg)
+	}
+
+	report, err = l.Apply([]byte(`
+server:
+  port: 9090
+logConfig:
+  level: DEBUG
+`), "merge")
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if !reflect.DeepEqual(report.Changed, []string{"logConfig", "server"}) {
+		t.Errorf("Expected changed [logConfig server], got %v", report.Changed)
+	}
+	if srv.Port != 9090 || atomicSrv.Get().Port != 9090 || logCfg.Level != "DEBUG" {
+		t.Errorf("Expected update to be applied, got %+v %+v %+v", srv, atomicSrv.Get(), logCfg)
+	}
+
+	report, err = l.Apply([]byte("server:\n  port: 9090\n"), "merge")
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if len(report.Changed) != 0 {
+		t.Errorf("Expected no changes, got %v", report.Changed)
+	}
+}
@@ -0,0 +1,58 @@
+package config
+
+import "fmt"
+
+// mergeTree 将 src 递归合并到 dst 中并返回 dst
+// 两边都是 map 时递归合并；其余情况（包括数组和标量）由 src 整体覆盖 dst
+func mergeTree(dst, src map[string]interface{}) map[string]interface{} {
+	if dst == nil {
+		dst = make(map[string]interface{}, len(src))
+	}
+	for k, v := range src {
+		srcMap, srcIsMap := toStringMap(v)
+		dstMap, dstIsMap := toStringMap(dst[k])
+		if srcIsMap && dstIsMap {
+			dst[k] = mergeTree(dstMap, srcMap)
+			continue
+		}
+		dst[k] = copyValue(v)
+	}
+	return dst
+}
+
+// copyValue 深拷贝 yaml 解析出的值，避免合并后的树与来源共享 map 或数组
+func copyValue(v interface{}) interface{} {
+	if m, ok := toStringMap(v); ok {
+		out := make(map[string]interface{}, len(m))
+		for k, vv := range m {
+			out[k] = copyValue(vv)
+		}
+		return out
+	}
+	if s, ok := v.([]interface{}); ok {
+		out := make([]interface{}, len(s))
+		for i, vv := range s {
+			out[i] = copyValue(vv)
+		}
+		return out
+	}
+	return v
+}
+
+// toStringMap 将 yaml 解析出的 map 统一转换为 map[string]interface{}
+// yaml.v3 在 key 不全是字符串时会得到 map[interface{}]interface{}
+func toStringMap(v interface{}) (map[string]interface{}, bool) {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		return m, true
+	case config:
+		return m, true
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(m))
+		for k, vv := range m {
+			out[fmt.Sprint(k)] = vv
+		}
+		return out, true
+	}
+	return nil, false
+}
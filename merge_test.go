@@ -0,0 +1,131 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func parseTree(t *testing.T, s string) map[string]interface{} {
+	t.Helper()
+	tree := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(s), &tree); err != nil {
+		t.Fatalf("parse yaml failed: %v", err)
+	}
+	return tree
+}
+
+func TestMergeTree(t *testing.T) {
+	dst := parseTree(t, `
+server:
+  url: http://localhost:8080
+  name: app
+  port: 8080
+redis:
+  default:
+    addrs: [a:6379, b:6379]
+    password: admin
+    db: 2
+  session:
+    addrs: [c:6379]
+`)
+	src := parseTree(t, `
+server:
+  port: 9090
+redis:
+  default:
+    addrs: [x:6379]
+    db: 3
+  cache:
+    addrs: [y:6379]
+`)
+
+	got := mergeTree(dst, src)
+	want := parseTree(t, `
+server:
+  url: http://localhost:8080
+  name: app
+  port: 9090
+redis:
+  default:
+    addrs: [x:6379]
+    password: admin
+    db: 3
+  session:
+    addrs: [c:6379]
+  cache:
+    addrs: [y:6379]
+`)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeTree mismatch\ngot:  %v\nwant: %v", got, want)
+	}
+}
+
+func TestMergeTreeReplacesTypes(t *testing.T) {
+	dst := parseTree(t, `
+a:
+  b: 1
+c: [1, 2]
+d: scalar
+`)
+	src := parseTree(t, `
+a: replaced
+c:
+  nested: true
+d: [1]
+`)
+
+	got := mergeTree(dst, src)
+	want := parseTree(t, `
+a: replaced
+c:
+  nested: true
+d: [1]
+`)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeTree mismatch\ngot:  %v\nwant: %v", got, want)
+	}
+}
+
+func TestMergeTreeDoesNotAliasSource(t *testing.T) {
+	src := parseTree(t, `
+redis:
+  default:
+    addrs: [a:6379]
+`)
+	dst := mergeTree(nil, src)
+
+	// 修改合并结果不应影响来源
+	dst["redis"].(map[string]interface{})["default"].(map[string]interface{})["db"] = 5
+	dst["redis"].(map[string]interface{})["default"].(map[string]interface{})["addrs"].([]interface{})[0] = "b:6379"
+
+	def := src["redis"].(map[string]interface{})["default"].(map[string]interface{})
+	if _, ok := def["db"]; ok {
+		t.Error("Expected source map to be untouched")
+	}
+	if def["addrs"].([]interface{})[0] != "a:6379" {
+		t.Errorf("Expected source array to be untouched, got %v", def["addrs"])
+	}
+}
+
+func TestMergeTreeNonStringKeys(t *testing.T) {
+	dst := parseTree(t, `
+codes:
+  1: one
+  2: two
+`)
+	src := parseTree(t, `
+codes:
+  2: deux
+`)
+
+	got := mergeTree(dst, src)
+	codes, ok := got["codes"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected codes to be normalized to map[string]interface{}, got %T", got["codes"])
+	}
+	if codes["1"] != "one" || codes["2"] != "deux" {
+		t.Errorf("Unexpected merge result: %v", codes)
+	}
+}
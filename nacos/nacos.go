@@ -0,0 +1,272 @@
+// Package nacos 通过 Nacos open API 读取并长轮询配置，Client 是优先级为 config.PriorityRemote 的配置来源
+//
+// 用法:
+//
+//	if err := nacos.Start(ctx); err != nil {
+//		log.Printf("nacos: %v", err)
+//	}
+//
+// 或者添加到自己的 Loader:
+//
+//	l := config.New(config.WithSource("nacos:"+cfg.DataId, config.PriorityRemote, nacos.NewClient(cfg)))
+//	l.WatchSources(ctx)
+package nacos
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/teatak/config/v2"
+	"github.com/teatak/config/v2/internal/remote"
+	"github.com/teatak/config/v2/sections"
+)
+
+const (
+	// DefaultGroup 是未指定 group 时使用的分组
+	DefaultGroup = "DEFAULT_GROUP"
+	// DefaultPort 是未指定端口时使用的 Nacos 端口
+	DefaultPort = 8848
+	// DefaultPollTimeout 是长轮询的默认超时
+	DefaultPollTimeout = 30 * time.Second
+)
+
+// Config 描述要读取的 Nacos 配置
+type Config struct {
+	Addr        string        // 服务地址，包含 context path，例如 http://127.0.0.1:8848/nacos
+	NamespaceId string        // 命名空间，空表示 public
+	DataId      string        // 配置的 data ID
+	Group       string        // 分组，默认 DEFAULT_GROUP
+	Username    string        // 开启鉴权时的用户名，为空时不登录
+	Password    string        // 开启鉴权时的密码
+	Mode        string        // merge（默认）按优先级覆盖本地配置；overwrite 丢弃本地配置，见 config.Overwriter
+	PollTimeout time.Duration // 长轮询超时，默认 30s
+	HTTPClient  *http.Client  // 默认使用 http.DefaultClient
+}
+
+// FromSection 根据 sections.Nacos 生成 Config
+// ipAddr 可以带上 http:// 或 https://，不带时使用 http；端口默认 8848
+func FromSection() Config {
+	n := sections.Nacos
+	addr := n.IpAddr
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	port := n.Port
+	if port == 0 {
+		port = DefaultPort
+	}
+	return Config{
+		Addr:        fmt.Sprintf("%s:%d/nacos", addr, port),
+		NamespaceId: n.NamespaceId,
+		DataId:      n.DataId,
+		Group:       n.Group,
+		Username:    n.Username,
+		Password:    n.Password.Value(),
+		Mode:        n.Mode,
+	}
+}
+
+// Client 通过 Nacos open API 读取和监听一个配置，实现 config.Source、config.Watcher 和 config.Overwriter
+type Client struct {
+	cfg Config
+
+	mu      sync.Mutex
+	token   string    // 登录得到的 accessToken
+	expires time.Time // token 的过期时间
+	md5     string    // 最近一次读取到的内容的 md5，用于长轮询
+}
+
+// NewClient 创建一个 Client，未设置的字段使用默认值
+func NewClient(cfg Config) *Client {
+	cfg.Addr = strings.TrimRight(cfg.Addr, "/")
+	if cfg.Group == "" {
+		cfg.Group = DefaultGroup
+	}
+	if cfg.PollTimeout <= 0 {
+		cfg.PollTimeout = DefaultPollTimeout
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	return &Client{cfg: cfg}
+}
+
+// Start 在 sections.Nacos.Enable 为 true 时将 Nacos 配置以 config.PriorityRemote 添加到默认 Loader，
+// 并在后台监听变化直到 ctx 结束；首次读取或应用失败时不添加来源，返回错误
+// 来源名称带有 data ID，例如 "nacos:app.yaml"，记录在 Explain 和历史版本中
+func Start(ctx context.Context) error {
+	if !sections.Nacos.Enable {
+		return nil
+	}
+	c := NewClient(FromSection())
+	return remote.Start(ctx, config.Default(), c.source(), c)
+}
+
+// source 返回来源名称，例如 "nacos:app.yaml"
+func (c *Client) source() string {
+	return "nacos:" + c.cfg.DataId
+}
+
+// Load 读取配置的当前内容并解析为配置树，内容可以是 yaml 或 JSON
+func (c *Client) Load(ctx context.Context) (map[string]any, error) {
+	content, err := c.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data, err := config.Parse([]byte(content))
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", c.cfg.DataId, err)
+	}
+	return data, nil
+}
+
+// Watch 持续长轮询配置的变化，每次变化都推送最新的内容，直到 ctx 结束
+func (c *Client) Watch(ctx context.Context) <-chan config.Event {
+	return remote.Watch(ctx, func(ctx context.Context) (map[string]any, error) {
+		changed, err := c.Listen(ctx)
+		if err != nil || !changed {
+			return nil, err
+		}
+		return c.Load(ctx)
+	})
+}
+
+// Overwrite 在 Mode 为 overwrite 时返回 true
+func (c *Client) Overwrite() bool {
+	return c.cfg.Mode == "overwrite"
+}
+
+// Get 读取配置的当前内容，并记录其 md5 用于之后的长轮询
+func (c *Client) Get(ctx context.Context) (string, error) {
+	q := url.Values{}
+	q.Set("dataId", c.cfg.DataId)
+	q.Set("group", c.cfg.Group)
+	if c.cfg.NamespaceId != "" {
+		q.Set("tenant", c.cfg.NamespaceId)
+	}
+	body, err := c.do(ctx, func(token string) (*http.Request, error) {
+		if token != "" {
+			q.Set("accessToken", token)
+		}
+		return http.NewRequestWithContext(ctx, http.MethodGet, c.cfg.Addr+"/v1/cs/configs?"+q.Encode(), nil)
+	})
+	if err != nil {
+		return "", fmt.Errorf("get %s: %w", c.cfg.DataId, err)
+	}
+	sum := md5.Sum(body)
+	c.mu.Lock()
+	c.md5 = hex.EncodeToString(sum[:])
+	c.mu.Unlock()
+	return string(body), nil
+}
+
+// Listen 发起一次长轮询，配置在 PollTimeout 内发生变化时返回 true
+func (c *Client) Listen(ctx context.Context) (bool, error) {
+	c.mu.Lock()
+	sum := c.md5
+	c.mu.Unlock()
+
+	// 格式为 dataId^2group^2md5[^2tenant]^1
+	listening := c.cfg.DataId + "\x02" + c.cfg.Group + "\x02" + sum
+	if c.cfg.NamespaceId != "" {
+		listening += "\x02" + c.cfg.NamespaceId
+	}
+	listening += "\x01"
+	form := url.Values{"Listening-Configs": {listening}}
+
+	body, err := c.do(ctx, func(token string) (*http.Request, error) {
+		q := url.Values{}
+		if token != "" {
+			q.Set("accessToken", token)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.Addr+"/v1/cs/configs/listener?"+q.Encode(), strings.NewReader(form.Encode()))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Long-Pulling-Timeout", fmt.Sprint(c.cfg.PollTimeout.Milliseconds()))
+		return req, nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("listen %s: %w", c.cfg.DataId, err)
+	}
+	return strings.TrimSpace(string(body)) != "", nil
+}
+
+// do 发送请求并返回响应内容，token 失效（403）时重新登录并重试一次
+func (c *Client) do(ctx context.Context, build func(token string) (*http.Request, error)) ([]byte, error) {
+	for retry := 0; ; retry++ {
+		token, err := c.accessToken(ctx, retry > 0)
+		if err != nil {
+			return nil, err
+		}
+		req, err := build(token)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.cfg.HTTPClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			return body, nil
+		case resp.StatusCode == http.StatusForbidden && c.cfg.Username != "" && retry == 0:
+			continue
+		}
+		return nil, fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+}
+
+// accessToken 返回有效的 accessToken，未配置用户名时返回空，force 为 true 时重新登录
+func (c *Client) accessToken(ctx context.Context, force bool) (string, error) {
+	if c.cfg.Username == "" {
+		return "", nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !force && c.token != "" && time.Now().Before(c.expires) {
+		return c.token, nil
+	}
+
+	form := url.Values{"username": {c.cfg.Username}, "password": {c.cfg.Password}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.Addr+"/v1/auth/login", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := c.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("login: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("login: unexpected status %s: %s", resp.Status, strings.TrimSpace(string(b)))
+	}
+	var result struct {
+		AccessToken string `json:"accessToken"`
+		TokenTtl    int64  `json:"tokenTtl"` // 秒
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("login: %w", err)
+	}
+	c.token = result.AccessToken
+	// 提前刷新，避免请求途中过期
+	c.expires = time.Now().Add(time.Duration(result.TokenTtl) * time.Second * 9 / 10)
+	return c.token, nil
+}
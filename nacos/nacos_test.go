@@ -0,0 +1,194 @@
+package nacos
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/teatak/config/v2"
+)
+
+// fakeNacos 实现 Nacos open API 中登录、读取配置和长轮询的部分
+type fakeNacos struct {
+	mu      sync.Mutex
+	content string
+	changed chan struct{} // 每次内容变化时关闭并替换
+	logins  int
+}
+
+func newFakeNacos(content string) *fakeNacos {
+	return &fakeNacos{content: content, changed: make(chan struct{})}
+}
+
+func (f *fakeNacos) set(content string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.content = content
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+func (f *fakeNacos) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/nacos/v1/auth/login" {
+		if r.FormValue("username") != "nacos" || r.FormValue("password") != "secret" {
+			http.Error(w, "unknown user!", http.StatusForbidden)
+			return
+		}
+		f.mu.Lock()
+		f.logins++
+		f.mu.Unlock()
+		w.Write([]byte(`{"accessToken":"token-1","tokenTtl":18000,"globalAdmin":true}`))
+		return
+	}
+	if r.URL.Query().Get("accessToken") != "token-1" {
+		http.Error(w, "token invalid!", http.StatusForbidden)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/nacos/v1/cs/configs":
+		q := r.URL.Query()
+		if q.Get("dataId") != "app.yaml" || q.Get("group") != "DEFAULT_GROUP" || q.Get("tenant") != "dev" {
+			http.Error(w, "config data not exist", http.StatusNotFound)
+			return
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		w.Write([]byte(f.content))
+	case "/nacos/v1/cs/configs/listener":
+		fields := strings.Split(strings.TrimSuffix(r.FormValue("Listening-Configs"), "\x01"), "\x02")
+		if len(fields) != 4 || fields[0] != "app.yaml" || fields[3] != "dev" {
+			http.Error(w, "invalid listening configs", http.StatusBadRequest)
+			return
+		}
+		timeout, err := time.ParseDuration(r.Header.Get("Long-Pulling-Timeout") + "ms")
+		if err != nil {
+			http.Error(w, "invalid timeout", http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		sum := md5.Sum([]byte(f.content))
+		changed := f.changed
+		f.mu.Unlock()
+		if hex.EncodeToString(sum[:]) == fields[2] {
+			select {
+			case <-changed:
+			case <-time.After(timeout):
+				return
+			case <-r.Context().Done():
+				return
+			}
+		}
+		w.Write([]byte("app.yaml%02DEFAULT_GROUP%02dev%01\n"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+type server struct {
+	Name string `yaml:"name"`
+	Port int    `yaml:"port"`
+}
+
+func newLoader(t *testing.T, opts ...config.Option) *config.Loader {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.yaml")
+	if err := writeFile(path, "nacos:\n  dataId: app.yaml\nserver:\n  name: local\n  port: 8080\n"); err != nil {
+		t.Fatal(err)
+	}
+	return config.New(append(opts, config.WithConfigPath(path))...)
+}
+
+func TestClient(t *testing.T) {
+	fake := newFakeNacos("server:\n  port: 9090\n")
+	ts := httptest.NewServer(fake)
+	defer ts.Close()
+
+	c := NewClient(Config{
+		Addr:        ts.URL + "/nacos",
+		NamespaceId: "dev",
+		DataId:      "app.yaml",
+		Username:    "nacos",
+		Password:    "secret",
+		PollTimeout: 100 * time.Millisecond,
+	})
+	l := newLoader(t, config.WithSource(c.source(), config.PriorityRemote, c))
+	srv := config.RegisterTo(l, &server{})
+	if srv.Port != 9090 || srv.Name != "local" {
+		t.Errorf("Expected merged config, got %+v", srv)
+	}
+	changes, cancelWatch := l.Watch("server")
+	defer cancelWatch()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	l.WatchSources(ctx)
+
+	fake.set("server:\n  port: 7070\n")
+	select {
+	case <-changes:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for nacos change")
+	}
+	if srv.Port != 7070 {
+		t.Errorf("Expected port 7070 after change, got %d", srv.Port)
+	}
+	if got := l.Explain("server.port"); got[len(got)-1].Source != "nacos:app.yaml" {
+		t.Errorf("Expected port from nacos, got %v", got)
+	}
+	fake.mu.Lock()
+	logins := fake.logins
+	fake.mu.Unlock()
+	if logins != 1 {
+		t.Errorf("Expected token to be reused, got %d logins", logins)
+	}
+}
+
+func TestClientOverwrite(t *testing.T) {
+	ts := httptest.NewServer(newFakeNacos("{\"server\": {\"port\": 9090}}"))
+	defer ts.Close()
+
+	c := NewClient(Config{Addr: ts.URL + "/nacos/", NamespaceId: "dev", DataId: "app.yaml", Username: "nacos", Password: "secret", Mode: "overwrite"})
+	l := newLoader(t, config.WithSource("nacos", config.PriorityRemote, c))
+	srv := config.RegisterTo(l, &server{})
+	if srv.Port != 9090 || srv.Name != "" {
+		t.Errorf("Expected overwrite to drop local config, got %+v", srv)
+	}
+	if got := l.Explain("nacos.dataId"); len(got) != 1 {
+		t.Errorf("Expected nacos section to survive overwrite, got %v", got)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	ts := httptest.NewServer(newFakeNacos("server:\n  port: 9090\n"))
+	defer ts.Close()
+
+	c := NewClient(Config{Addr: ts.URL + "/nacos", NamespaceId: "dev", DataId: "app.yaml", Username: "nacos", Password: "wrong"})
+	if _, err := c.Load(context.Background()); err == nil || !strings.Contains(err.Error(), "login") {
+		t.Errorf("Expected login error, got %v", err)
+	}
+
+	c = NewClient(Config{Addr: ts.URL + "/nacos", NamespaceId: "dev", DataId: "missing.yaml", Username: "nacos", Password: "secret"})
+	if _, err := c.Load(context.Background()); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+}
+
+func TestFromSection(t *testing.T) {
+	cfg := FromSection()
+	if cfg.Addr != "http://:8848/nacos" {
+		t.Errorf("Expected default port in address, got %s", cfg.Addr)
+	}
+}
+
+func writeFile(path, content string) error {
+	return os.WriteFile(path, []byte(content), 0o644)
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+)
+
+// pgpKeyring 读取本地的 PGP 私钥：CONFIG_PGP_KEY_FILE 指向的文件，以及 GnuPG 1.x 的 secring.gpg（$GNUPGHOME 或 ~/.gnupg 下），默认文件不存在时忽略
+// 密钥文件由 gpg --export-secret-keys 导出，可以是 ASCII armor 格式；带口令的私钥使用 CONFIG_PGP_PASSPHRASE 解开
+// GnuPG 2.1 之后的 private-keys-v1.d 不是 OpenPGP 格式，需要先导出
+func pgpKeyring() (openpgp.EntityList, error) {
+	var ring openpgp.EntityList
+	add := func(file string, required bool) error {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			if !required && errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		keys, err := parsePGPKeys(b, os.Getenv("CONFIG_PGP_PASSPHRASE"))
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		ring = append(ring, keys...)
+		return nil
+	}
+	if file := os.Getenv("CONFIG_PGP_KEY_FILE"); file != "" {
+		if err := add(file, true); err != nil {
+			return nil, err
+		}
+	}
+	home := os.Getenv("GNUPGHOME")
+	if home == "" {
+		if dir, err := os.UserHomeDir(); err == nil {
+			home = filepath.Join(dir, ".gnupg")
+		}
+	}
+	if home != "" {
+		if err := add(filepath.Join(home, "secring.gpg"), false); err != nil {
+			return nil, err
+		}
+	}
+	return ring, nil
+}
+
+// parsePGPKeys 解析导出的私钥，passphrase 不为空时解开带口令的私钥
+func parsePGPKeys(b []byte, passphrase string) (openpgp.EntityList, error) {
+	var (
+		keys openpgp.EntityList
+		err  error
+	)
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("-----BEGIN")) {
+		keys, err = openpgp.ReadArmoredKeyRing(bytes.NewReader(b))
+	} else {
+		keys, err = openpgp.ReadKeyRing(bytes.NewReader(b))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if passphrase != "" {
+		for _, e := range keys {
+			if err := e.DecryptPrivateKeys([]byte(passphrase)); err != nil {
+				return nil, fmt.Errorf("key %X: %w", e.PrimaryKey.Fingerprint, err)
+			}
+		}
+	}
+	return keys, nil
+}
+
+// pgpDecrypt 使用 ring 中的私钥解密 ASCII armor 格式的 PGP 消息
+func pgpDecrypt(message string, ring openpgp.EntityList) ([]byte, error) {
+	block, err := armor.Decode(strings.NewReader(message))
+	if err != nil {
+		return nil, fmt.Errorf("pgp: %w", err)
+	}
+	md, err := openpgp.ReadMessage(block.Body, ring, nil, nil)
+	if err != nil {
+		for _, e := range ring {
+			if e.PrivateKey != nil && e.PrivateKey.Encrypted {
+				return nil, fmt.Errorf("pgp: %w (key %X is passphrase protected, set CONFIG_PGP_PASSPHRASE)", err, e.PrimaryKey.Fingerprint)
+			}
+		}
+		return nil, fmt.Errorf("pgp: %w", err)
+	}
+	return io.ReadAll(md.UnverifiedBody)
+}
@@ -0,0 +1,271 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OriginKind 表示配置值的来源类型
+type OriginKind string
+
+const (
+	FromDefault OriginKind = "default" // 结构体字段的 default 标签
+	FromFile    OriginKind = "file"    // 配置文件
+	FromFlag    OriginKind = "flag"    // 命令行参数，见 FlagSource
+	FromSource  OriginKind = "source"  // 通过 WithSource / AddSource 添加的其他来源
+	FromUpdate  OriginKind = "update"  // UpdateConfig 或远程配置推送
+	FromEnv     OriginKind = "env"     // 环境变量覆盖
+)
+
+// Origin 描述一个来源对某个配置项的一次设置
+type Origin struct {
+	Kind   OriginKind
+	Source string      // 文件路径、环境变量名、参数名、带 default 标签的字段（例如 "redis.DB"）、来源名称或更新来源（例如 "update"）
+	Line   int         // 在文件或更新内容中的行号，未知时为 0
+	Value  interface{} // 该来源设置的原始值，敏感项已脱敏
+
+	sensitive bool // 值是 SOPS 加密文件中解密得到的明文
+}
+
+// String 以 "config/dev.yaml:12 = 3"、"env APP_REDIS__DEFAULT__DB = 5" 的形式描述来源
+func (o Origin) String() string {
+	where := o.Source
+	switch o.Kind {
+	case FromEnv:
+		where = "env " + o.Source
+	case FromFlag:
+		where = "flag -" + o.Source
+	case FromDefault:
+		where = "default tag on " + o.Source
+	}
+	if o.Line > 0 {
+		where = fmt.Sprintf("%s:%d", where, o.Line)
+	}
+	return fmt.Sprintf("%s = %v", where, o.Value)
+}
+
+// provenance 记录原始配置树中每个叶子配置项的覆盖链，key 为以 "." 分隔的路径
+// 与配置树一样，提交后不再原地修改
+type provenance map[string][]Origin
+
+// with 返回叠加一层来源记录之后的结果，merged 是叠加之后的原始配置树，p 本身保持不变
+// 在 merged 中已经不是叶子的旧记录会被丢弃，例如 log.level 在 log 被整体替换为标量之后
+func (p provenance) with(merged config, layer provenance) provenance {
+	next := make(provenance, len(p)+len(layer))
+	for path, chain := range p {
+		if isLeafPath(merged, path) {
+			next[path] = chain
+		}
+	}
+	for path, chain := range layer {
+		prev := next[path]
+		next[path] = append(prev[:len(prev):len(prev)], chain...)
+	}
+	return next
+}
+
+// originsOf 为 tree 中的每个叶子配置项记录由 origin 描述的来源
+func originsOf(tree config, origin func(path string, v interface{}) Origin) provenance {
+	p := provenance{}
+	walkLeaves("", tree, func(path string, v interface{}) {
+		p[path] = []Origin{origin(path, v)}
+	})
+	return p
+}
+
+// fileOrigin 返回配置文件 path 中配置项的来源，lines 和 decrypted 是 parseFile 得到的行号和 SOPS 解密的路径
+// 数组中的 map 含有解密的值时整个数组视为敏感
+func fileOrigin(path string, lines map[string]int, decrypted map[string]bool) func(string, interface{}) Origin {
+	return func(key string, v interface{}) Origin {
+		o := Origin{Kind: FromFile, Source: path, Line: lines[key], Value: v}
+		for p := range decrypted {
+			if p == key || strings.HasPrefix(p, key+".") {
+				o.sensitive = true
+				break
+			}
+		}
+		return o
+	}
+}
+
+// updateOrigin 返回来自 source 的更新内容中配置项的来源
+func updateOrigin(source string, lines map[string]int) func(string, interface{}) Origin {
+	return func(key string, v interface{}) Origin {
+		return Origin{Kind: FromUpdate, Source: source, Line: lines[key], Value: v}
+	}
+}
+
+// keep 返回只保留 prefix 子树的来源记录
+func (p provenance) keep(prefix string) provenance {
+	next := provenance{}
+	for path, chain := range p {
+		if path == prefix || strings.HasPrefix(path, prefix+".") {
+			next[path] = chain
+		}
+	}
+	return next
+}
+
+// walkLeaves 按路径遍历 node 中的叶子配置项，map 以外的值（包括数组）都视为叶子
+func walkLeaves(path string, node interface{}, fn func(path string, v interface{})) {
+	m, ok := toStringMap(node)
+	if !ok {
+		if path != "" {
+			fn(path, node)
+		}
+		return
+	}
+	for k, v := range m {
+		walkLeaves(joinPath(path, k), v, fn)
+	}
+}
+
+// isLeafPath 判断 path 在 tree 中是否存在且不是 map
+func isLeafPath(tree config, path string) bool {
+	var node interface{} = map[string]interface{}(tree)
+	for _, seg := range strings.Split(path, ".") {
+		m, ok := toStringMap(node)
+		if !ok {
+			return false
+		}
+		if node, ok = m[seg]; !ok {
+			return false
+		}
+	}
+	_, isMap := toStringMap(node)
+	return !isMap
+}
+
+// decodeDocument 将 yaml 文档节点解码到 out 中，并把每个叶子配置项所在的行号记录到 lines
+// 空文档不修改 out；!file 标签由调用方处理，见 markFileTags 和 rejectFileTags
+func decodeDocument(doc *yaml.Node, out *config, lines map[string]int) error {
+	if doc.Kind == 0 {
+		return nil
+	}
+	if err := doc.Decode(out); err != nil {
+		return err
+	}
+	recordLines("", doc, lines)
+	return nil
+}
+
+// recordLines 记录 node 中每个叶子配置项 key 所在的行号，合并键 (<<) 引入的值先记录，显式的 key 覆盖它们
+func recordLines(path string, node *yaml.Node, lines map[string]int) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, n := range node.Content {
+			recordLines(path, n, lines)
+		}
+	case yaml.AliasNode:
+		recordLines(path, node.Alias, lines)
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if k := node.Content[i]; k.Tag == "!!merge" {
+				recordLines(path, node.Content[i+1], lines)
+			}
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			k, v := node.Content[i], node.Content[i+1]
+			if k.Tag == "!!merge" {
+				continue
+			}
+			child := joinPath(path, k.Value)
+			for v.Kind == yaml.AliasNode {
+				v = v.Alias
+			}
+			if v.Kind == yaml.MappingNode {
+				recordLines(child, v, lines)
+				continue
+			}
+			lines[child] = k.Line
+		}
+	case yaml.SequenceNode:
+		// 合并键的值可以是 map 的列表，前面的优先
+		for i := len(node.Content) - 1; i >= 0; i-- {
+			recordLines(path, node.Content[i], lines)
+		}
+	}
+}
+
+// Explain 返回默认 Loader 中配置项的覆盖链，详见 Loader.Explain
+func Explain(path string) []Origin {
+	return std.Explain(path)
+}
+
+// Explain 返回配置项 path（例如 "redis.default.db"）的覆盖链，按优先级从低到高排列，最后一项生效：
+// default 标签、按优先级排列的各个来源（配置文件、命令行参数、远程配置等）、UpdateConfig，最后是环境变量覆盖
+// 环境变量按当前进程环境计算；path 没有任何来源时返回 nil
+func (l *Loader) Explain(path string) []Origin {
+	l.ensureLoaded()
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	segs := strings.Split(path, ".")
+	var chain []Origin
+	types := l.sectionTypes()
+	if field, tag, ok := defaultTag(types[segs[0]], segs[1:]); ok {
+		chain = append(chain, Origin{Kind: FromDefault, Source: field, Value: tag})
+	}
+	chain = append(chain, l.origins[path]...)
+
+	if prefix := l.envPrefixOrEnv(); prefix != "" {
+		tree := copyValue(l.data).(map[string]interface{})
+		set := applyEnvOverlay(tree, prefix, os.Environ(), types)
+		if key, ok := set[path]; ok {
+			_, _, v, _ := lookupPath(tree, segs)
+			chain = append(chain, Origin{Kind: FromEnv, Source: key, Value: v})
+		}
+	}
+
+	if len(chain) == 0 {
+		return nil
+	}
+	isSecret := l.isSecret()
+	out := make([]Origin, len(chain))
+	for i, o := range chain {
+		o.Value = redactValue(segs, o.Value, isSecret)
+		out[i] = o
+	}
+	return out
+}
+
+// defaultTag 返回类型 t 中 segs 路径对应的字段（形如 "redis.DB"）及其 default 标签，map 类型消耗一段任意的 key
+func defaultTag(t reflect.Type, segs []string) (field, tag string, ok bool) {
+	for i, seg := range segs {
+		t = indirectType(t)
+		if t == nil {
+			return "", "", false
+		}
+		switch t.Kind() {
+		case reflect.Map:
+			t = t.Elem()
+		case reflect.Struct:
+			f, found := structField(t, seg)
+			if !found {
+				return "", "", false
+			}
+			if i == len(segs)-1 {
+				tag, ok = f.Tag.Lookup("default")
+				return t.Name() + "." + f.Name, tag, ok
+			}
+			t = f.Type
+		default:
+			return "", "", false
+		}
+	}
+	return "", "", false
+}
+
+// structField 按 yaml 名称查找结构体字段
+func structField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		if yamlFieldName(t.Field(i)) == name {
+			return t.Field(i), true
+		}
+	}
+	return reflect.StructField{}, false
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type provenanceRedis struct {
+	Addrs    []string `yaml:"addrs"`
+	Password string   `yaml:"password"`
+	DB       int      `yaml:"db" default:"0"`
+}
+
+func TestExplain(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml": "config: common,dev\nredis:\n  default:\n    db: 1\n",
+		"common.yaml": `
+base: &base
+  db: 2
+redis:
+  default:
+    <<: *base
+    password: p1
+`,
+		"dev.yaml": "redis:\n  default:\n    addrs: [a:6379]\n    db: 3\n",
+	})
+	app := filepath.Join(dir, "app.yaml")
+	common := filepath.Join(dir, "common.yaml")
+	dev := filepath.Join(dir, "dev.yaml")
+
+	t.Setenv("PROV_REDIS__DEFAULT__DB", "5")
+	l := New(WithConfigPath(app), WithEnvPrefix("PROV"))
+	RegisterMapTo[*provenanceRedis](l, "redis")
+
+	want := []Origin{
+		{Kind: FromDefault, Source: "provenanceRedis.DB", Value: "0"},
+		{Kind: FromFile, Source: app, Line: 4, Value: 1},
+		{Kind: FromFile, Source: common, Line: 3, Value: 2},
+		{Kind: FromFile, Source: dev, Line: 4, Value: 3},
+		{Kind: FromEnv, Source: "PROV_REDIS__DEFAULT__DB", Value: int64(5)},
+	}
+	if got := l.Explain("redis.default.db"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Explain mismatch\ngot:  %v\nwant: %v", got, want)
+	}
+
+	// 敏感项脱敏
+	want = []Origin{{Kind: FromFile, Source: common, Line: 7, Value: Redacted}}
+	if got := l.Explain("redis.default.password"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Explain password mismatch\ngot:  %v\nwant: %v", got, want)
+	}
+
+	if got := l.Explain("redis.default.missing"); got != nil {
+		t.Errorf("Expected nil for unknown key, got %v", got)
+	}
+
+	if err := l.UpdateConfig([]byte("redis:\n  default:\n    addrs: [b:6379]\n"), "merge"); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	want = []Origin{
+		{Kind: FromFile, Source: dev, Line: 3, Value: []interface{}{"a:6379"}},
+		{Kind: FromUpdate, Source: "update", Line: 3, Value: []interface{}{"b:6379"}},
+	}
+	if got := l.Explain("redis.default.addrs"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Explain after update mismatch\ngot:  %v\nwant: %v", got, want)
+	}
+
+	// overwrite 丢弃文件的来源，被删除的 key 只剩 default 标签
+	if err := l.UpdateConfig([]byte("redis:\n  default:\n    addrs: [c:6379]\n"), "overwrite"); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	want = []Origin{{Kind: FromUpdate, Source: "update", Line: 3, Value: []interface{}{"c:6379"}}}
+	if got := l.Explain("redis.default.addrs"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Explain after overwrite mismatch\ngot:  %v\nwant: %v", got, want)
+	}
+	want = []Origin{
+		{Kind: FromDefault, Source: "provenanceRedis.DB", Value: "0"},
+		{Kind: FromEnv, Source: "PROV_REDIS__DEFAULT__DB", Value: int64(5)},
+	}
+	if got := l.Explain("redis.default.db"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Explain db after overwrite mismatch\ngot:  %v\nwant: %v", got, want)
+	}
+
+	// 回滚恢复对应版本的来源
+	hist := l.History()
+	if err := l.Rollback(hist[0].Version); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if got := l.Explain("redis.default.addrs"); len(got) != 1 || got[0].Source != dev {
+		t.Errorf("Expected addrs from %s after rollback, got %v", dev, got)
+	}
+}
+
+func TestExplainReplacedSubtree(t *testing.T) {
+	l := newTestLoader(t)
+	if err := l.UpdateConfig([]byte("logConfig: disabled\n"), "merge"); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	if got := l.Explain("logConfig.level"); got != nil {
+		t.Errorf("Expected no origins for replaced key, got %v", got)
+	}
+	if got := l.Explain("logConfig"); len(got) != 1 || got[0].Value != "disabled" {
+		t.Errorf("Expected logConfig from update, got %v", got)
+	}
+}
+
+func TestOriginString(t *testing.T) {
+	cases := map[string]Origin{
+		"config/dev.yaml:12 = 3":         {Kind: FromFile, Source: "config/dev.yaml", Line: 12, Value: 3},
+		"env APP_REDIS__DEFAULT__DB = 5": {Kind: FromEnv, Source: "APP_REDIS__DEFAULT__DB", Value: 5},
+		"default tag on redis.DB = 0":    {Kind: FromDefault, Source: "redis.DB", Value: "0"},
+		"update:2 = [a:6379]":            {Kind: FromUpdate, Source: "update", Line: 2, Value: []interface{}{"a:6379"}},
+	}
+	for want, o := range cases {
+		if got := o.String(); got != want {
+			t.Errorf("Expected %q, got %q", want, got)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Secret 是敏感的字符串配置，打印和序列化时输出 Redacted，避免日志中出现明文
+// fmt 的 %v / %+v / %#v、encoding/json 和 yaml 都会被脱敏，使用 Value 或 string(s) 取得明文
+// 空值原样输出，便于看出配置缺失
+//
+//	type redis struct {
+//		Password config.Secret `yaml:"password"`
+//	}
+//
+// Secret 类型的字段以及带 secret:"true" 标签的字段在所属 Loader 的 History、Apply 返回的 Report、
+// Explain 和 Dump 中都会脱敏，不影响其他 Loader 和包级的 Diff
+type Secret string
+
+// Value 返回明文
+func (s Secret) Value() string {
+	return string(s)
+}
+
+// String 返回脱敏后的值
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return Redacted
+}
+
+// GoString 返回脱敏后的值，用于 %#v
+func (s Secret) GoString() string {
+	return fmt.Sprintf("config.Secret(%q)", s.String())
+}
+
+// MarshalJSON 输出脱敏后的字符串
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// MarshalYAML 输出脱敏后的字符串
+func (s Secret) MarshalYAML() (interface{}, error) {
+	return s.String(), nil
+}
+
+var secretType = reflect.TypeOf(Secret(""))
+
+// secretFields 返回类型 t 中需要脱敏的字段相对 t 的路径，map 的 key 用 "*" 表示
+// 字段类型为 Secret（包括 []Secret 等容器）或带 secret:"true" 标签时视为敏感
+func secretFields(t reflect.Type) [][]string {
+	var out [][]string
+	collectSecretFields(t, nil, map[reflect.Type]bool{}, &out)
+	return out
+}
+
+func collectSecretFields(t reflect.Type, path []string, seen map[reflect.Type]bool, out *[][]string) {
+	t = indirectType(t)
+	if t == nil {
+		return
+	}
+	switch t.Kind() {
+	case reflect.Map:
+		collectSecretFields(t.Elem(), append(path[:len(path):len(path)], "*"), seen, out)
+	case reflect.Slice, reflect.Array:
+		// 列表的元素与列表本身使用同一路径脱敏
+		collectSecretFields(t.Elem(), path, seen, out)
+	case reflect.Struct:
+		if seen[t] {
+			return
+		}
+		seen[t] = true
+		defer delete(seen, t)
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name := yamlFieldName(f)
+			if name == "" {
+				continue
+			}
+			sub := append(path[:len(path):len(path)], name)
+			if f.Tag.Get("secret") == "true" || isSecretType(f.Type) {
+				*out = append(*out, sub)
+				continue
+			}
+			collectSecretFields(f.Type, sub, seen, out)
+		}
+	}
+}
+
+// isSecretType 判断 t 是否为 Secret 或元素为 Secret 的容器
+func isSecretType(t reflect.Type) bool {
+	for {
+		t = indirectType(t)
+		switch t.Kind() {
+		case reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+			continue
+		}
+		return t == secretType
+	}
+}
+
+// secretFieldPaths 返回已注册 section 中敏感字段的完整路径，调用方必须持有锁
+func (l *Loader) secretFieldPaths() [][]string {
+	var out [][]string
+	for name, t := range l.sectionTypes() {
+		for _, p := range secretFields(t) {
+			out = append(out, append([]string{name}, p...))
+		}
+	}
+	return out
+}
+
+// markSensitive 记录值来自 ENC[...] 或 secret 文件的配置路径，调用方必须持有写锁
+func (l *Loader) markSensitive(path []string) {
+	if l.sensitive == nil {
+		l.sensitive = map[string]bool{}
+	}
+	l.sensitive[strings.Join(path, ".")] = true
+}
+
+// markSensitiveOrigins 记录值来自 SOPS 加密文件的配置路径，调用方必须持有写锁
+func (l *Loader) markSensitiveOrigins(origins provenance) {
+	for path, chain := range origins {
+		if slices.ContainsFunc(chain, func(o Origin) bool { return o.sensitive }) {
+			l.markSensitive([]string{path})
+		}
+	}
+}
+
+// isSecret 返回判断该 Loader 中配置路径是否敏感的函数，调用方必须持有锁，且只能在持有锁期间使用
+// 除 IsSecretPath 外，还包括已注册 section 中 Secret 类型和带 secret:"true" 标签的字段，
+// 以及值曾经来自 ENC[...]、secret 文件或 SOPS 加密文件的配置项，不论 key 的名称
+func (l *Loader) isSecret() func(path []string) bool {
+	fields := l.secretFieldPaths()
+	return func(path []string) bool {
+		if IsSecretPath(path) || l.sensitive[strings.Join(path, ".")] {
+			return true
+		}
+		return slices.ContainsFunc(fields, func(p []string) bool { return matchPath(p, path) })
+	}
+}
+
+// Dump 返回默认 Loader 的配置，详见 Loader.Dump
+func Dump() map[string]interface{} {
+	return std.Dump()
+}
+
+// Dump 返回 section 实际使用的配置树的副本，即环境变量覆盖、${...} 展开、secret 文件和解密之后的结果，
+// 敏感配置项已脱敏，包括 IsSecretPath 匹配的路径、Secret 字段以及来自加密值和 secret 文件的值，可以直接输出到日志或调试接口
+func (l *Loader) Dump() map[string]interface{} {
+	l.ensureLoaded()
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	isSecret := l.isSecret()
+	out := make(map[string]interface{}, len(l.effective))
+	for k, v := range l.effective {
+		out[k] = redactValue([]string{k}, plainValue(v), isSecret)
+	}
+	return out
+}
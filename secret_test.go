@@ -0,0 +1,126 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+type vault struct {
+	Cert    Secret            `yaml:"cert"`
+	Backups []Secret          `yaml:"backups"`
+	DSN     string            `yaml:"dsn" secret:"true"`
+	Region  string            `yaml:"region"`
+	Peers   map[string]*vault `yaml:"peers"`
+}
+
+func TestSecretFormat(t *testing.T) {
+	v := vault{Cert: "pem-plaintext", Backups: []Secret{"b-plaintext"}, Region: "cn"}
+	for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+		if out := fmt.Sprintf(format, v); strings.Contains(out, "plaintext") {
+			t.Errorf("%s leaked the secret: %s", format, out)
+		}
+	}
+	if got := fmt.Sprintf("%#v", v.Cert); got != `config.Secret("******")` {
+		t.Errorf("Unexpected GoString %s", got)
+	}
+	if got := fmt.Sprint(Secret("")); got != "" {
+		t.Errorf("Expected empty secret to print empty, got %q", got)
+	}
+
+	b, _ := json.Marshal(v)
+	if strings.Contains(string(b), "plaintext") || !strings.Contains(string(b), `"Cert":"******"`) {
+		t.Errorf("Unexpected json %s", b)
+	}
+	y, _ := yaml.Marshal(v)
+	if strings.Contains(string(y), "plaintext") || !strings.Contains(string(y), "cert: '******'") {
+		t.Errorf("Unexpected yaml %s", y)
+	}
+
+	// 解码和取值不受影响
+	var decoded vault
+	if err := yaml.Unmarshal([]byte("cert: abc\nbackups: [x]\n"), &decoded); err != nil || decoded.Cert.Value() != "abc" || decoded.Backups[0] != "x" {
+		t.Errorf("Unexpected decode %#v (%v)", decoded.Cert.Value(), err)
+	}
+}
+
+func TestSecretFields(t *testing.T) {
+	got := secretFields(reflect.TypeOf(map[string]*vault{}))
+	// 递归的类型不再展开，peers 中的字段不会重复注册
+	want := [][]string{{"*", "cert"}, {"*", "backups"}, {"*", "dsn"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestRegisterRedactsSecrets(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml": "vault:\n  main:\n    cert: old-cert\n    dsn: old-dsn\n    region: cn\n",
+	})
+	l := New(WithConfigPath(filepath.Join(dir, "app.yaml")))
+	v := RegisterMapTo[*vault](l, "vault")
+	if v["main"].Cert.Value() != "old-cert" || v["main"].DSN != "old-dsn" {
+		t.Fatalf("Unexpected section %+v", v["main"])
+	}
+
+	report, err := l.Apply([]byte("vault:\n  main:\n    cert: new-cert\n    dsn: new-dsn\n    region: us\n"), "merge")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hist := l.History()
+	out := fmt.Sprint(report.Changes, hist[0].Changes, l.Dump(), l.Explain("vault.main.dsn"))
+	if strings.Contains(out, "-cert") || strings.Contains(out, "-dsn") {
+		t.Errorf("Expected secrets to be redacted, got %s", out)
+	}
+	if !strings.Contains(out, "us") {
+		t.Errorf("Expected non-secret values to be kept, got %s", out)
+	}
+
+	// Secret 字段只在注册它的 Loader 中脱敏
+	if IsSecretPath([]string{"vault", "main", "cert"}) {
+		t.Error("Expected registration not to change the global redaction rules")
+	}
+	other := New(WithConfigPath(filepath.Join(dir, "app.yaml")))
+	if d := fmt.Sprint(other.Dump()); !strings.Contains(d, "old-cert") {
+		t.Errorf("Expected other loader to be unaffected, got %s", d)
+	}
+}
+
+func TestRedactSensitiveValues(t *testing.T) {
+	_, key := testKey(t)
+	dsn, _ := EncryptValue(key, "mysql://root:pw@db/app")
+	newDSN, _ := EncryptValue(key, "mysql://root:pw2@db/app")
+	ref := filepath.Join(t.TempDir(), "secret.txt")
+	os.WriteFile(ref, []byte("from-file\n"), 0o600)
+
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml": "mysql:\n  default:\n    dsn: " + dsn + "\n    host: db\napi:\n  ref: !file " + ref + "\n",
+	})
+	l := New(WithConfigPath(filepath.Join(dir, "app.yaml")), WithSecretKey(key))
+	if err := l.Err(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	report, err := l.Apply([]byte("mysql:\n  default:\n    dsn: "+newDSN+"\n"), "merge")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// key 的名称不敏感，但值来自加密值或 secret 文件
+	dump := l.Dump()
+	out := fmt.Sprint(dump, report.Changes, l.History(), l.Explain("mysql.default.dsn"), l.Explain("api.ref"))
+	for _, leaked := range []string{"mysql://", "from-file", "ENC[", ref} {
+		if strings.Contains(out, leaked) {
+			t.Errorf("Expected %s to be redacted, got %s", leaked, out)
+		}
+	}
+	mysql := dump["mysql"].(map[string]interface{})["default"].(map[string]interface{})
+	if mysql["dsn"] != Redacted || mysql["host"] != "db" {
+		t.Errorf("Unexpected dump %v", mysql)
+	}
+}
@@ -3,16 +3,16 @@ package sections
 import "github.com/teatak/config/v2"
 
 type auth struct {
-	JWTSecret      string            `yaml:"jwt_secret" json:"jwt_secret"`
-	InternalSecret []string          `yaml:"internal_secret"`
+	JWTSecret      config.Secret     `yaml:"jwt_secret" json:"jwt_secret"`
+	InternalSecret []config.Secret   `yaml:"internal_secret"`
 	OAuth2         map[string]OAuth2 `yaml:"oauth2" json:"oauth2"`
 }
 
 type OAuth2 struct {
-	ClientID     string   `yaml:"client_id"`
-	ClientSecret string   `yaml:"client_secret"`
-	RedirectURL  string   `yaml:"redirect_url"`
-	Scopes       []string `yaml:"scopes"`
+	ClientID     string        `yaml:"client_id"`
+	ClientSecret config.Secret `yaml:"client_secret"`
+	RedirectURL  string        `yaml:"redirect_url"`
+	Scopes       []string      `yaml:"scopes"`
 }
 
 var Auth = config.Register(&auth{})
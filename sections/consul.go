@@ -0,0 +1,20 @@
+package sections
+
+import (
+	"time"
+
+	"github.com/teatak/config/v2"
+)
+
+type consul struct {
+	Enable     bool          `yaml:"enable"`
+	Address    string        `yaml:"address" default:"http://127.0.0.1:8500"` // 不带协议时使用 http
+	Token      config.Secret `yaml:"token"`
+	Datacenter string        `yaml:"datacenter"`
+	Key        string        `yaml:"key"`               // 保存整个 YAML 配置的 key，与 prefix 二选一
+	Prefix     string        `yaml:"prefix"`            // key 前缀，app/server/port 映射为 server.port
+	Wait       time.Duration `yaml:"wait" default:"5m"` // blocking query 的最长等待时间
+	Mode       string        `yaml:"mode"`              // merge or overwrite
+}
+
+var Consul = config.Register(&consul{})
@@ -0,0 +1,15 @@
+package sections
+
+import "github.com/teatak/config/v2"
+
+type etcd struct {
+	Enable    bool          `yaml:"enable"`
+	Endpoints []string      `yaml:"endpoints"` // 例如 http://127.0.0.1:2379，不带协议时使用 http
+	Username  string        `yaml:"username"`
+	Password  config.Secret `yaml:"password"`
+	Key       string        `yaml:"key"`    // 保存整个 YAML 配置的 key，与 prefix 二选一
+	Prefix    string        `yaml:"prefix"` // key 前缀，/app/server/port 映射为 server.port
+	Mode      string        `yaml:"mode"`   // merge or overwrite
+}
+
+var Etcd = config.Register(&etcd{})
@@ -3,8 +3,8 @@ package sections
 import "github.com/teatak/config/v2"
 
 type github struct {
-	ClientID     string `yaml:"clientID,omitempty"`
-	ClientSecret string `yaml:"clientSecret,omitempty"`
+	ClientID     string        `yaml:"clientID,omitempty"`
+	ClientSecret config.Secret `yaml:"clientSecret,omitempty"`
 }
 
 var Github = config.Register(&github{})
@@ -0,0 +1,20 @@
+package sections
+
+import (
+	"time"
+
+	"github.com/teatak/config/v2"
+)
+
+type httpConfig struct {
+	Enable   bool          `yaml:"enable"`
+	URL      string        `yaml:"url" validate:"url"`
+	Interval time.Duration `yaml:"interval" default:"30s"` // 轮询间隔
+	Token    config.Secret `yaml:"token"`                  // bearer token，与 username/password 二选一
+	Username string        `yaml:"username"`               // basic auth
+	Password config.Secret `yaml:"password"`
+	CAFile   string        `yaml:"caFile"` // 自定义 CA 证书（PEM），用于内部签发的 https 证书
+	Mode     string        `yaml:"mode"`   // merge or overwrite
+}
+
+var HttpConfig = config.Register(&httpConfig{})
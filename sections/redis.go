@@ -11,11 +11,11 @@ type redis struct {
 	// ClientName 和 `Options` 相同，会对每个Node节点的每个网络连接配置
 	ClientName string `yaml:"clientName,omitempty"`
 	// 设置 DB, 只针对 `Redis Client` 和 `Failover Client`
-	DB               int    `yaml:"db,omitempty"`
-	Username         string `yaml:"username,omitempty"`
-	Password         string `yaml:"password,omitempty"`
-	SentinelUsername string `yaml:"sentinelUsername,omitempty"`
-	SentinelPassword string `yaml:"sentinelPassword,omitempty"`
+	DB               int           `yaml:"db,omitempty"`
+	Username         string        `yaml:"username,omitempty"`
+	Password         config.Secret `yaml:"password,omitempty"`
+	SentinelUsername string        `yaml:"sentinelUsername,omitempty"`
+	SentinelPassword config.Secret `yaml:"sentinelPassword,omitempty"`
 }
 
 var Redis = config.RegisterMap[*redis]("redis")
@@ -1,9 +1,11 @@
 package sections_test
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/teatak/config/v2/sections"
@@ -50,9 +52,17 @@ func TestAuthSection(t *testing.T) {
 	if sections.Auth == nil {
 		t.Fatal("Expected Auth to be initialized")
 	}
-	t.Logf("Auth: JWTSecret=%s..., OAuth2 providers=%d",
-		sections.Auth.JWTSecret[:min(10, len(sections.Auth.JWTSecret))],
-		len(sections.Auth.OAuth2))
+	saved := *sections.Auth
+	defer func() { *sections.Auth = saved }()
+	sections.Auth.JWTSecret = "jwt-plaintext"
+	sections.Auth.OAuth2 = map[string]sections.OAuth2{"github": {ClientID: "id", ClientSecret: "oauth-plaintext"}}
+
+	// 打印 section 不会输出明文
+	out := fmt.Sprintf("%v %+v %#v", sections.Auth, sections.Auth, sections.Auth)
+	t.Logf("Auth: %+v", sections.Auth)
+	if strings.Contains(out, "plaintext") {
+		t.Errorf("Expected secrets to be redacted, got %s", out)
+	}
 }
 
 func TestAllSectionsInitialized(t *testing.T) {
@@ -63,6 +73,9 @@ func TestAllSectionsInitialized(t *testing.T) {
 	}{
 		{"Server", sections.Server != nil},
 		{"Nacos", sections.Nacos != nil},
+		{"Etcd", sections.Etcd != nil},
+		{"Consul", sections.Consul != nil},
+		{"HttpConfig", sections.HttpConfig != nil},
 		{"Log", sections.Log != nil},
 		{"Auth", sections.Auth != nil},
 		{"Smtp", sections.Smtp != nil},
@@ -86,10 +99,3 @@ func TestAllSectionsInitialized(t *testing.T) {
 
 	t.Log("✅ All sections initialized successfully")
 }
-
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
-}
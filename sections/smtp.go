@@ -3,10 +3,10 @@ package sections
 import "github.com/teatak/config/v2"
 
 type smtp struct {
-	Address  string `yaml:"address,omitempty"`
-	Name     string `yaml:"name,omitempty"`
-	Username string `yaml:"username,omitempty"`
-	Password string `yaml:"password,omitempty"`
+	Address  string        `yaml:"address,omitempty"`
+	Name     string        `yaml:"name,omitempty"`
+	Username string        `yaml:"username,omitempty"`
+	Password config.Secret `yaml:"password,omitempty"`
 }
 
 var Smtp = config.Register(&smtp{})
@@ -0,0 +1,376 @@
+package config
+
+import (
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"hash"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// sopsMetadata 是 SOPS 加密文件中 sops 字段里需要用到的部分
+type sopsMetadata struct {
+	Keys              sopsKeys   `yaml:",inline"`
+	KeyGroups         []sopsKeys `yaml:"key_groups"`
+	LastModified      string     `yaml:"lastmodified"`
+	MAC               string     `yaml:"mac"`
+	UnencryptedSuffix string     `yaml:"unencrypted_suffix"`
+	EncryptedSuffix   string     `yaml:"encrypted_suffix"`
+	UnencryptedRegex  string     `yaml:"unencrypted_regex"`
+	EncryptedRegex    string     `yaml:"encrypted_regex"`
+	MACOnlyEncrypted  bool       `yaml:"mac_only_encrypted"`
+	// 按注释决定是否加密的规则需要解析注释，不支持
+	UnencryptedCommentRegex string `yaml:"unencrypted_comment_regex"`
+	EncryptedCommentRegex   string `yaml:"encrypted_comment_regex"`
+}
+
+// sopsMACOnlyEncryptedInit 是 mac_only_encrypted 时 MAC 的初始内容，即 sha256("sops")，与 SOPS 相同
+var sopsMACOnlyEncryptedInit = []byte{
+	0x8a, 0x3f, 0xd2, 0xad, 0x54, 0xce, 0x66, 0x52, 0x7b, 0x10, 0x34, 0xf3, 0xd1, 0x47, 0xbe, 0x0b,
+	0x0b, 0x97, 0x5b, 0x3b, 0xf4, 0x4f, 0x72, 0xc6, 0xfd, 0xad, 0xec, 0x81, 0x76, 0xf2, 0x7d, 0x69,
+}
+
+// sopsKeys 是加密了数据密钥的主密钥，支持 age 和 PGP，其余类型只用于生成错误信息
+type sopsKeys struct {
+	Age []struct {
+		Recipient string `yaml:"recipient"`
+		Enc       string `yaml:"enc"`
+	} `yaml:"age"`
+	PGP []struct {
+		Fingerprint string `yaml:"fp"`
+		Enc         string `yaml:"enc"`
+	} `yaml:"pgp"`
+	KMS     []yaml.Node `yaml:"kms"`
+	GCPKMS  []yaml.Node `yaml:"gcp_kms"`
+	AzureKV []yaml.Node `yaml:"azure_kv"`
+	Vault   []yaml.Node `yaml:"hc_vault"`
+}
+
+// sopsMetadataOf 返回文档根节点中的 sops 字段，文件不是 SOPS 加密文件时返回 nil
+func sopsMetadataOf(doc *yaml.Node) *yaml.Node {
+	root := doc
+	if root.Kind == yaml.DocumentNode && len(root.Content) == 1 {
+		root = root.Content[0]
+	}
+	if root.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "sops" || root.Content[i+1].Kind != yaml.MappingNode {
+			continue
+		}
+		meta := root.Content[i+1]
+		for j := 0; j+1 < len(meta.Content); j += 2 {
+			if meta.Content[j].Value == "mac" {
+				return meta
+			}
+		}
+	}
+	return nil
+}
+
+// decryptSOPS 在内存中原地解密 SOPS 加密的 yaml 文档并校验 MAC，完成后删除 sops 字段
+// 元数据取自第一个带 sops 字段的文档，MAC 覆盖所有文档；docs 不是 SOPS 加密文件时什么都不做
+// 数据密钥通过本地的 age 身份或 PGP 私钥解开，不支持云 KMS 和 Vault
+// 返回解密的配置项路径，数组元素使用数组本身的路径
+func decryptSOPS(docs []*yaml.Node) (map[string]bool, error) {
+	var metaNode *yaml.Node
+	for _, doc := range docs {
+		if metaNode = sopsMetadataOf(doc); metaNode != nil {
+			break
+		}
+	}
+	if metaNode == nil {
+		return nil, nil
+	}
+	var meta sopsMetadata
+	if err := metaNode.Decode(&meta); err != nil {
+		return nil, fmt.Errorf("sops: metadata: %w", err)
+	}
+	key, err := meta.dataKey()
+	if err != nil {
+		return nil, err
+	}
+	rules, err := meta.rules()
+	if err != nil {
+		return nil, err
+	}
+
+	decrypted := map[string]bool{}
+	sum := sha512.New()
+	if meta.MACOnlyEncrypted {
+		sum.Write(sopsMACOnlyEncryptedInit)
+	}
+	for _, doc := range docs {
+		root := doc
+		if root.Kind == yaml.DocumentNode && len(root.Content) == 1 {
+			root = root.Content[0]
+		}
+		if root.Kind != yaml.MappingNode {
+			continue
+		}
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			if root.Content[i].Value == "sops" {
+				root.Content = append(root.Content[:i:i], root.Content[i+2:]...)
+				i -= 2
+				continue
+			}
+			if err := decryptSOPSNode(root.Content[i+1], []string{root.Content[i].Value}, key, rules, meta.MACOnlyEncrypted, sum, decrypted); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// MAC 以最后修改时间作为附加认证数据加密
+	lastModified, err := time.Parse(time.RFC3339, meta.LastModified)
+	if err != nil {
+		return nil, fmt.Errorf("sops: lastmodified: %w", err)
+	}
+	mac, _, err := decryptValue(key, meta.MAC, []byte(lastModified.Format(time.RFC3339)))
+	if err != nil {
+		return nil, fmt.Errorf("sops: mac: %w", err)
+	}
+	if mac != fmt.Sprintf("%X", sum.Sum(nil)) {
+		return nil, errors.New("sops: MAC mismatch, the file has been modified")
+	}
+	return decrypted, nil
+}
+
+// dataKey 使用本地的 age 身份或 PGP 私钥解开数据密钥，任一接收者成功即可
+func (m *sopsMetadata) dataKey() ([]byte, error) {
+	groups := []sopsKeys{m.Keys}
+	switch {
+	case len(m.KeyGroups) == 1:
+		groups = append(groups, m.KeyGroups[0])
+	case len(m.KeyGroups) > 1:
+		return nil, errors.New("sops: shamir key groups are not supported")
+	}
+	var ages, pgps, others []string
+	for _, g := range groups {
+		for _, a := range g.Age {
+			ages = append(ages, a.Enc)
+		}
+		for _, p := range g.PGP {
+			pgps = append(pgps, p.Enc)
+		}
+		for _, k := range []struct {
+			name string
+			n    int
+		}{{"kms", len(g.KMS)}, {"gcp_kms", len(g.GCPKMS)}, {"azure_kv", len(g.AzureKV)}, {"hc_vault", len(g.Vault)}} {
+			if k.n > 0 {
+				others = append(others, k.name)
+			}
+		}
+	}
+	if len(ages) == 0 && len(pgps) == 0 {
+		if len(others) > 0 {
+			return nil, fmt.Errorf("sops: only age and pgp recipients are supported, found %s", strings.Join(others, ", "))
+		}
+		return nil, errors.New("sops: no age or pgp recipients")
+	}
+
+	var errs []error
+	try := func(key []byte, err error) []byte {
+		if err == nil && len(key) != SecretKeySize {
+			err = errors.New("malformed data key")
+		}
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		return key
+	}
+	if len(ages) > 0 {
+		ids, err := ageIdentities()
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for _, enc := range ages {
+			if len(ids) == 0 {
+				break
+			}
+			if key := try(ageDecrypt([]byte(enc), ids)); key != nil {
+				return key, nil
+			}
+		}
+		if err == nil && len(ids) == 0 {
+			errs = append(errs, errors.New("age: no identities found, set SOPS_AGE_KEY or SOPS_AGE_KEY_FILE"))
+		}
+	}
+	if len(pgps) > 0 {
+		ring, err := pgpKeyring()
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for _, enc := range pgps {
+			if len(ring) == 0 {
+				break
+			}
+			if key := try(pgpDecrypt(enc, ring)); key != nil {
+				return key, nil
+			}
+		}
+		if err == nil && len(ring) == 0 {
+			errs = append(errs, errors.New("pgp: no private keys found, set CONFIG_PGP_KEY_FILE"))
+		}
+	}
+	return nil, fmt.Errorf("sops: cannot decrypt the data key: %w", errors.Join(errs...))
+}
+
+// sopsRules 决定某个路径的值是否被加密
+type sopsRules func(path []string) bool
+
+func (m *sopsMetadata) rules() (sopsRules, error) {
+	if m.UnencryptedCommentRegex != "" || m.EncryptedCommentRegex != "" {
+		return nil, errors.New("sops: unencrypted_comment_regex and encrypted_comment_regex are not supported")
+	}
+	suffix, encSuffix := m.UnencryptedSuffix, m.EncryptedSuffix
+	var unencRe, encRe *regexp.Regexp
+	for _, r := range []struct {
+		expr string
+		re   **regexp.Regexp
+	}{{m.UnencryptedRegex, &unencRe}, {m.EncryptedRegex, &encRe}} {
+		if r.expr == "" {
+			continue
+		}
+		re, err := regexp.Compile(r.expr)
+		if err != nil {
+			return nil, fmt.Errorf("sops: %w", err)
+		}
+		*r.re = re
+	}
+	// 与 SOPS 一样，没有任何规则时默认不加密以 _unencrypted 结尾的 key
+	if suffix == "" && encSuffix == "" && unencRe == nil && encRe == nil {
+		suffix = "_unencrypted"
+	}
+	return func(path []string) bool {
+		encrypted := true
+		if suffix != "" {
+			for _, p := range path {
+				if strings.HasSuffix(p, suffix) {
+					encrypted = false
+					break
+				}
+			}
+		}
+		if encSuffix != "" {
+			encrypted = false
+			for _, p := range path {
+				if strings.HasSuffix(p, encSuffix) {
+					encrypted = true
+					break
+				}
+			}
+		}
+		if unencRe != nil {
+			for _, p := range path {
+				if unencRe.MatchString(p) {
+					encrypted = false
+					break
+				}
+			}
+		}
+		if encRe != nil {
+			encrypted = false
+			for _, p := range path {
+				if encRe.MatchString(p) {
+					encrypted = true
+					break
+				}
+			}
+		}
+		return encrypted
+	}, nil
+}
+
+// decryptSOPSNode 按文件中的顺序解密 node 下的所有值，并把明文写入 MAC 的 hash，解密的路径记录到 decrypted
+// 与 SOPS 一样，数组元素使用数组本身的路径作为附加认证数据，null 不加密也不参与 MAC
+func decryptSOPSNode(node *yaml.Node, path []string, key []byte, encrypted sopsRules, macOnlyEncrypted bool, sum hash.Hash, decrypted map[string]bool) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			p := append(path[:len(path):len(path)], node.Content[i].Value)
+			if err := decryptSOPSNode(node.Content[i+1], p, key, encrypted, macOnlyEncrypted, sum, decrypted); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			if err := decryptSOPSNode(item, path, key, encrypted, macOnlyEncrypted, sum, decrypted); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		if node.Tag == "!!null" {
+			return nil
+		}
+		enc := encrypted(path)
+		var v interface{}
+		if enc {
+			plaintext, typ, err := decryptValue(key, node.Value, []byte(strings.Join(path, ":")+":"))
+			if err != nil {
+				return fmt.Errorf("sops: %s: %w", strings.Join(path, "."), err)
+			}
+			if v, err = sopsValue(typ, plaintext); err != nil {
+				return fmt.Errorf("sops: %s: %w", strings.Join(path, "."), err)
+			}
+			decrypted[strings.Join(path, ".")] = true
+			node.Value, node.Style = plaintext, 0
+			node.Tag = map[string]string{"int": "!!int", "float": "!!float", "bool": "!!bool", "time": "!!timestamp"}[typ]
+			if node.Tag == "" {
+				node.Tag = "!!str"
+			}
+		} else if err := node.Decode(&v); err != nil {
+			return fmt.Errorf("sops: %s: %w", strings.Join(path, "."), err)
+		}
+		if enc || !macOnlyEncrypted {
+			sum.Write(sopsBytes(v))
+		}
+	}
+	return nil
+}
+
+// sopsValue 按 type 字段把明文转换为 SOPS 加密前的值
+func sopsValue(typ, plaintext string) (interface{}, error) {
+	switch typ {
+	case "int":
+		return strconv.Atoi(plaintext)
+	case "float":
+		return strconv.ParseFloat(plaintext, 64)
+	case "bool":
+		return strconv.ParseBool(plaintext)
+	case "time":
+		var t time.Time
+		err := t.UnmarshalText([]byte(plaintext))
+		return t, err
+	case "str", "bytes":
+		return plaintext, nil
+	}
+	return nil, fmt.Errorf("unsupported encrypted type %q", typ)
+}
+
+// sopsBytes 返回值参与 MAC 计算的字节，与 SOPS 的 ToBytes 一致
+func sopsBytes(v interface{}) []byte {
+	switch v := v.(type) {
+	case string:
+		return []byte(v)
+	case int:
+		return []byte(strconv.Itoa(v))
+	case float64:
+		return []byte(strconv.FormatFloat(v, 'f', -1, 64))
+	case bool:
+		if v {
+			return []byte("True")
+		}
+		return []byte("False")
+	case time.Time:
+		b, _ := v.MarshalText()
+		return b
+	}
+	return []byte(fmt.Sprint(v))
+}
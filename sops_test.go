@@ -0,0 +1,223 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testdata/sops 下的文件由 sops 3.13、age-keygen 和 gpg 生成，yaml 和 json 文件使用 testdata/sops/age.key 加密，pgp.yaml 使用 pgp.asc 和 pgp-protected.asc 加密
+
+// useAgeKey 只使用测试身份，避免读取用户配置目录下的 keys.txt 和 GnuPG 的私钥
+func useAgeKey(t *testing.T) {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("GNUPGHOME", t.TempDir())
+	t.Setenv("SOPS_AGE_KEY", "")
+	t.Setenv("SOPS_AGE_KEY_FILE", filepath.Join("testdata", "sops", "age.key"))
+	t.Setenv("CONFIG_PGP_KEY_FILE", "")
+	t.Setenv("CONFIG_PGP_PASSPHRASE", "")
+}
+
+// writeSOPSChain 将加密文件放在 common.yaml 旁边，组成 config: common,<name> 的文件链
+func writeSOPSChain(t *testing.T, name, content string) string {
+	t.Helper()
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml":     "config: common," + name + "\n",
+		"common.yaml":  "server:\n  name: common\n  environment: prod\n",
+		name + ".yaml": content,
+	})
+	return filepath.Join(dir, "app.yaml")
+}
+
+func readFixture(t *testing.T, name string) string {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("testdata", "sops", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestSOPSFile(t *testing.T) {
+	useAgeKey(t)
+	l := New(WithConfigPath(writeSOPSChain(t, "prod", readFixture(t, "prod.yaml"))))
+	srv := RegisterTo(l, &server{})
+	rds := RegisterMapTo[*redis](l, "redis")
+	if err := l.Err(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if srv.Name != "prod" || srv.Port != 9443 || srv.Environment != "prod" {
+		t.Errorf("Unexpected server %+v", srv)
+	}
+	if len(srv.AllowOrigins) != 2 || srv.AllowOrigins[1] != "https://b.example.com" {
+		t.Errorf("Unexpected allowOrigins %v", srv.AllowOrigins)
+	}
+	if r := rds.Default(); r.Password != "p@ss: word" || r.Addrs[0] != "10.0.0.1:6379" {
+		t.Errorf("Unexpected redis %+v", r)
+	}
+
+	// 解密后的类型与加密前一致，sops 字段被删除
+	l.mu.RLock()
+	data := l.data
+	l.mu.RUnlock()
+	s := data["server"].(map[string]interface{})
+	if s["debug"] != false || s["ratio"] != 0.75 || s["url_unencrypted"] != "https://prod.example.com" {
+		t.Errorf("Unexpected decrypted values %v", s)
+	}
+	if _, ok := data["sops"]; ok {
+		t.Error("Expected sops metadata to be removed")
+	}
+
+	// 解密得到的值不论 key 的名称都会脱敏，未加密的值照常输出
+	out := fmt.Sprint(l.Dump(), l.History(), l.Explain("server.port"), l.Explain("server.allowOrigins"))
+	for _, leaked := range []string{"9443", "b.example.com", "10.0.0.1"} {
+		if strings.Contains(out, leaked) {
+			t.Errorf("Expected %s to be redacted, got %s", leaked, out)
+		}
+	}
+	if !strings.Contains(out, "https://prod.example.com") {
+		t.Errorf("Expected unencrypted value to be kept, got %s", out)
+	}
+}
+
+func TestSOPSEncryptedRegex(t *testing.T) {
+	useAgeKey(t)
+	l := New(WithConfigPath(writeSOPSChain(t, "partial", readFixture(t, "partial.yaml"))))
+	rds := RegisterMapTo[*redis](l, "redis")
+	if err := l.Err(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if r := rds.Default(); r.Password != "hunter2" || r.DB != 3 {
+		t.Errorf("Unexpected redis %+v", r)
+	}
+}
+
+func TestSOPSErrors(t *testing.T) {
+	prod := readFixture(t, "prod.yaml")
+	tests := []struct {
+		name    string
+		content string
+		key     string
+		want    string
+	}{
+		{
+			name:    "tampered plaintext",
+			content: strings.Replace(prod, "https://prod.example.com", "https://evil.example.com", 1),
+			want:    "MAC mismatch",
+		},
+		{
+			name:    "removed value",
+			content: strings.Replace(prod, "    empty: null\n", "", 1),
+			want:    "",
+		},
+		{
+			// 值与路径绑定，挪到其他 key 下无法解密
+			name:    "moved value",
+			content: strings.Replace(prod, "    name: ENC", "    nick: ENC", 1),
+			want:    "server.nick",
+		},
+		{
+			name:    "wrong identity",
+			content: prod,
+			key:     "AGE-SECRET-KEY-1E7C0A2MXEV3DHM03XNQY9MS4LY8W4H0NTD40FJDWAUKFR796ZYGQ39TYRU",
+			want:    "no identity matched",
+		},
+		{
+			name:    "kms only",
+			content: "server:\n  name: ENC[AES256_GCM,data:AA==,iv:AA==,tag:AA==,type:str]\nsops:\n  kms:\n    - arn: arn:aws:kms:us-east-1:000000000000:key/x\n  lastmodified: \"2026-10-18T10:16:49Z\"\n  mac: ENC[AES256_GCM,data:AA==,iv:AA==,tag:AA==,type:str]\n",
+			want:    "only age and pgp recipients are supported, found kms",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useAgeKey(t)
+			if tt.key != "" {
+				t.Setenv("SOPS_AGE_KEY_FILE", "")
+				t.Setenv("SOPS_AGE_KEY", tt.key)
+			}
+			l := New(WithConfigPath(writeSOPSChain(t, "prod", tt.content)))
+			srv := RegisterTo(l, &server{})
+			err := l.Err()
+			if tt.want == "" {
+				// null 不参与 MAC，删除它不影响校验
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Expected error containing %q, got %v", tt.want, err)
+			}
+			// 解密失败的文件整个不参与合并
+			if srv.Name != "common" {
+				t.Errorf("Expected name from common.yaml, got %q", srv.Name)
+			}
+		})
+	}
+}
+
+func TestSOPSPGP(t *testing.T) {
+	// testdata/sops/pgp.yaml 加密给两把 PGP 密钥，pgp-protected.asc 的口令是 "correct horse"
+	tests := []struct {
+		name, key, passphrase, want string
+	}{
+		{name: "key file", key: "pgp.asc"},
+		{name: "protected key", key: "pgp-protected.asc", passphrase: "correct horse"},
+		{name: "missing passphrase", key: "pgp-protected.asc", want: "CONFIG_PGP_PASSPHRASE"},
+		{name: "wrong passphrase", key: "pgp-protected.asc", passphrase: "wrong", want: "pgp-protected.asc"},
+		{name: "no key", want: "no private keys found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useAgeKey(t)
+			if tt.key != "" {
+				t.Setenv("CONFIG_PGP_KEY_FILE", filepath.Join("testdata", "sops", tt.key))
+			}
+			t.Setenv("CONFIG_PGP_PASSPHRASE", tt.passphrase)
+			l := New(WithConfigPath(writeSOPSChain(t, "prod", readFixture(t, "pgp.yaml"))))
+			srv := RegisterTo(l, &server{})
+			rds := RegisterMapTo[*redis](l, "redis")
+			err := l.Err()
+			if tt.want != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.want) {
+					t.Fatalf("Expected error containing %q, got %v", tt.want, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if srv.Name != "pgp" || srv.Port != 9443 || rds.Default().Password != "p@ss" || rds.Default().DB != 4 {
+				t.Errorf("Unexpected server %+v, redis %+v", srv, rds.Default())
+			}
+		})
+	}
+}
+
+func TestSOPSGnuPGHome(t *testing.T) {
+	useAgeKey(t)
+	// GnuPG 1.x 的 secring.gpg 是二进制格式的私钥
+	armored := readFixture(t, "pgp.asc")
+	keys, err := parsePGPKeys([]byte(armored), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	home := os.Getenv("GNUPGHOME")
+	f, err := os.Create(filepath.Join(home, "secring.gpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := keys[0].SerializePrivate(f, nil); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	l := New(WithConfigPath(writeSOPSChain(t, "prod", readFixture(t, "pgp.yaml"))))
+	srv := RegisterTo(l, &server{})
+	if err := l.Err(); err != nil || srv.Name != "pgp" {
+		t.Errorf("Expected file to decrypt with secring.gpg, got %+v (%v)", srv, err)
+	}
+}
@@ -0,0 +1,446 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Source 是一个配置来源，Load 返回该来源当前的完整配置树
+// Loader 按优先级从低到高合并所有来源，高优先级的来源覆盖低优先级的来源
+type Source interface {
+	Load(ctx context.Context) (map[string]any, error)
+}
+
+// Watcher 是 Source 可选实现的接口，用于主动推送变化
+// 来源发生变化时发送 Event，ctx 结束后应关闭通道
+type Watcher interface {
+	Watch(ctx context.Context) <-chan Event
+}
+
+// Overwriter 是 Source 可选实现的接口，Overwrite 返回 true 时该来源以 overwrite 模式合并：
+// 与 UpdateConfig 的 overwrite 相同，丢弃优先级更低的来源中除远程配置连接信息（见 WithKeepOnOverwrite）以外的所有配置
+// 每次合并都会调用 Overwrite，结果应保持不变
+type Overwriter interface {
+	Overwrite() bool
+}
+
+// Event 是 Watcher 推送的一次变化
+type Event struct {
+	Data map[string]any // 来源最新的完整配置树；为 nil 且 Err 为 nil 时重新调用 Load
+	Err  error          // 来源出错，记录到 Errors 中，配置保持不变
+}
+
+// 内置来源的优先级，数值越大优先级越高，相同优先级按添加顺序排列
+// UpdateConfig 写入的内容优先于所有来源，WithEnvPrefix 启用的环境变量覆盖又优先于 UpdateConfig
+const (
+	PriorityFiles  = 100 // 配置文件链，即 CONFIG_PATH 指向的入口文件及其 config 字段列出的文件
+	PriorityFlags  = 200 // 命令行参数
+	PriorityRemote = 300 // Nacos、etcd 等远程配置
+)
+
+// filesSource 是内置配置文件链的来源名称
+const filesSource = "files"
+
+// layer 是配置栈中一个来源最近一次的内容，提交后不再修改
+type layer struct {
+	name     string
+	priority int
+	source   Source
+	data     config
+	origins  provenance
+	label    string // 最近一次加载的描述，记录在历史中，例如加载的文件列表
+}
+
+// stack 是某一时刻所有来源的内容，与配置树一样提交后不再修改
+type stack struct {
+	layers      []layer // 按优先级从低到高排列
+	updates     layer   // UpdateConfig 写入的内容，优先于所有来源
+	overwritten bool    // updates 是否以 overwrite 模式写入，此时来源只保留 Loader 的 keep 中的 key
+}
+
+// compose 按优先级合并所有层，返回原始配置树及其来源记录，keep 是 overwrite 时保留的顶层 key
+func (s stack) compose(keep []string) (config, provenance) {
+	tree, origins := config{}, provenance{}
+	for i, ly := range append(s.layers[:len(s.layers):len(s.layers)], s.updates) {
+		if ly.overwrites() || i == len(s.layers) && s.overwritten {
+			// overwrite 丢弃之前合并的所有内容，只保留远程配置的连接信息，新内容自带这些 key 时同样丢弃
+			kept, org := config{}, provenance{}
+			for _, key := range keep {
+				if v := tree.get(key); v != nil && ly.data.get(key) == nil {
+					kept[key] = v
+					org = org.with(kept, origins.keep(key))
+				}
+			}
+			tree, origins = kept, org
+		}
+		tree = mergeTree(tree, ly.data)
+		origins = origins.with(tree, ly.origins)
+	}
+	return tree, origins
+}
+
+// overwrites 判断该层是否以 overwrite 模式合并，见 Overwriter
+func (ly layer) overwrites() bool {
+	o, ok := ly.source.(Overwriter)
+	return ok && o.Overwrite()
+}
+
+// index 返回名称为 name 的来源的位置，不存在时返回 -1
+func (s stack) index(name string) int {
+	for i, ly := range s.layers {
+		if ly.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// replace 返回第 i 层替换为 ly 之后的 stack，s 本身保持不变
+func (s stack) replace(i int, ly layer) stack {
+	s.layers = append([]layer(nil), s.layers...)
+	s.layers[i] = ly
+	return s
+}
+
+// insert 返回按优先级插入 ly 之后的 stack，s 本身保持不变
+func (s stack) insert(ly layer) stack {
+	layers := append([]layer(nil), s.layers...)
+	i := sort.Search(len(layers), func(i int) bool { return layers[i].priority > ly.priority })
+	layers = append(layers, layer{})
+	copy(layers[i+1:], layers[i:])
+	layers[i] = ly
+	s.layers = layers
+	return s
+}
+
+// label 返回所有来源最近一次加载的描述
+func (s stack) label() string {
+	labels := make([]string, 0, len(s.layers))
+	for _, ly := range s.layers {
+		if ly.label != "" {
+			labels = append(labels, ly.label)
+		}
+	}
+	return strings.Join(labels, ",")
+}
+
+// layerLoader 由内置来源实现，除配置树外还提供每个配置项的来源和加载描述
+// 返回错误时仍然可以返回部分成功的内容，例如配置文件链中能够解析的文件
+type layerLoader interface {
+	loadLayer(ctx context.Context) (data config, origins provenance, label string, err error)
+}
+
+// load 从来源重新加载该层，失败时返回的层保持原来的内容，
+// 只有 layerLoader 会在返回错误的同时返回部分成功的内容，由调用方决定是否使用
+func (ly layer) load(ctx context.Context) (layer, error) {
+	if ll, ok := ly.source.(layerLoader); ok {
+		data, origins, label, err := ll.loadLayer(ctx)
+		if data != nil {
+			ly.data, ly.origins, ly.label = data, origins, label
+		}
+		return ly, err
+	}
+	data, err := ly.source.Load(ctx)
+	if err != nil {
+		return ly, &LoadError{Source: ly.name, Err: err}
+	}
+	return ly.with(data), nil
+}
+
+// with 返回内容替换为 data 之后的层
+func (ly layer) with(data map[string]any) layer {
+	tree := config(copyValue(map[string]interface{}(data)).(map[string]interface{}))
+	ly.data, ly.label = tree, ly.name
+	ly.origins = originsOf(tree, func(_ string, v interface{}) Origin {
+		return Origin{Kind: FromSource, Source: ly.name, Value: v}
+	})
+	return ly
+}
+
+// WithSource 在配置栈中添加一个来源，name 用于历史、Explain 和错误信息，priority 决定覆盖顺序
+// 用法: config.New(config.WithSource("flags", config.PriorityFlags, config.FlagSource(flag.CommandLine)))
+func WithSource(name string, priority int, src Source) Option {
+	return func(l *Loader) {
+		l.stack = l.stack.insert(layer{name: name, priority: priority, source: src})
+	}
+}
+
+// AddSource 在默认 Loader 的配置栈中添加一个来源，详见 Loader.AddSource
+func AddSource(ctx context.Context, name string, priority int, src Source) error {
+	return std.AddSource(ctx, name, priority, src)
+}
+
+// AddSource 在运行时添加一个来源：立即加载它，并以与 UpdateConfig 相同的事务方式应用
+// 加载或应用失败时来源不会被添加；name 已存在时返回错误
+func (l *Loader) AddSource(ctx context.Context, name string, priority int, src Source) error {
+	l.ensureLoaded()
+	err := l.addSource(ctx, name, priority, src)
+	l.notify()
+	return err
+}
+
+func (l *Loader) addSource(ctx context.Context, name string, priority int, src Source) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.stack.index(name) >= 0 || name == "update" {
+		return fmt.Errorf("config: source %s already exists", name)
+	}
+	ly, err := layer{name: name, priority: priority, source: src}.load(ctx)
+	if err != nil {
+		return err
+	}
+	_, _, err = l.applyStack("source:"+name, l.stack.insert(ly))
+	return err
+}
+
+// WatchSources 监听默认 Loader 中实现了 Watcher 的来源，详见 Loader.WatchSources
+func WatchSources(ctx context.Context) {
+	std.WatchSources(ctx)
+}
+
+// WatchSources 为配置栈中每个实现了 Watcher 的来源启动监听，直到 ctx 结束
+// 来源推送的内容替换该来源原来的内容，以与 UpdateConfig 相同的事务方式应用并通知订阅者
+// 推送的错误和应用失败都记录到 Errors 中，配置保持不变
+func (l *Loader) WatchSources(ctx context.Context) {
+	l.ensureLoaded()
+	l.mu.RLock()
+	layers := l.stack.layers
+	l.mu.RUnlock()
+	for _, ly := range layers {
+		if w, ok := ly.source.(Watcher); ok {
+			l.watchSource(ctx, ly.name, w)
+		}
+	}
+}
+
+// WatchSource 监听默认 Loader 中名称为 name 的来源，详见 Loader.WatchSource
+func WatchSource(ctx context.Context, name string) error {
+	return std.WatchSource(ctx, name)
+}
+
+// WatchSource 为配置栈中名称为 name 的来源启动监听，直到 ctx 结束，推送的处理同 WatchSources
+// 适合在 AddSource 之后单独监听新添加的来源；来源不存在或没有实现 Watcher 时返回错误
+func (l *Loader) WatchSource(ctx context.Context, name string) error {
+	l.ensureLoaded()
+	l.mu.RLock()
+	i := l.stack.index(name)
+	var src Source
+	if i >= 0 {
+		src = l.stack.layers[i].source
+	}
+	l.mu.RUnlock()
+	if i < 0 {
+		return fmt.Errorf("config: source %s not found", name)
+	}
+	w, ok := src.(Watcher)
+	if !ok {
+		return fmt.Errorf("config: source %s does not implement Watcher", name)
+	}
+	l.watchSource(ctx, name, w)
+	return nil
+}
+
+// watchSource 在后台应用 w 推送的变化，直到通道关闭
+func (l *Loader) watchSource(ctx context.Context, name string, w Watcher) {
+	go func(events <-chan Event) {
+		for ev := range events {
+			if err := l.applyEvent(ctx, name, ev); err != nil {
+				log.Printf("config: source %s: %v\n", name, err)
+				l.addError(err)
+			}
+		}
+	}(w.Watch(ctx))
+}
+
+// applyEvent 使用来源 name 推送的变化替换该来源的内容
+func (l *Loader) applyEvent(ctx context.Context, name string, ev Event) error {
+	if ev.Err != nil {
+		return &LoadError{Source: name, Err: ev.Err}
+	}
+	err := l.reloadSource(ctx, name, ev.Data)
+	l.notify()
+	return err
+}
+
+// reloadSource 用 data 替换来源 name 的内容，data 为 nil 时重新加载该来源
+func (l *Loader) reloadSource(ctx context.Context, name string, data map[string]any) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	i := l.stack.index(name)
+	if i < 0 {
+		return fmt.Errorf("config: source %s not found", name)
+	}
+	ly := l.stack.layers[i]
+	if data != nil {
+		ly = ly.with(data)
+	} else {
+		var err error
+		if ly, err = ly.load(ctx); err != nil {
+			return err
+		}
+	}
+	if _, _, err := l.applyStack("source:"+name, l.stack.replace(i, ly)); err != nil {
+		return &LoadError{Source: name, Err: err}
+	}
+	return nil
+}
+
+// fileChain 是内置的配置文件链：入口文件及其 config 字段（或环境变量 config、WithFiles）列出的文件
+type fileChain struct {
+	configPath string
+	files      []string
+
+	mu    sync.Mutex
+	paths []string // 最近一次加载尝试读取的文件，包括不存在的候选文件，供 WatchFiles 使用
+}
+
+func (c *fileChain) Load(ctx context.Context) (map[string]any, error) {
+	data, _, _, err := c.loadLayer(ctx)
+	return data, err
+}
+
+// loadLayer 按顺序独立解析并合并链中的文件，能解析的文件照常合并，所有错误汇总返回
+func (c *fileChain) loadLayer(ctx context.Context) (config, provenance, string, error) {
+	var errs []error
+	configPath := c.configPath
+	if configPath == "" {
+		configPath = os.Getenv("CONFIG_PATH")
+	}
+	if configPath == "" {
+		configPath = resolveFile("./config", "app")
+	}
+	configDir := filepath.Dir(configPath)
+
+	env := strings.Join(c.files, ",")
+	if env == "" {
+		env = os.Getenv("config")
+	}
+
+	tree, origins := config{}, provenance{}
+	var loaded, watched []string
+	add := func(path string, t config, lines map[string]int, decrypted map[string]bool) {
+		tree = mergeTree(tree, t)
+		origins = origins.with(tree, originsOf(t, fileOrigin(path, lines, decrypted)))
+		loaded = append(loaded, path)
+	}
+
+	if env == "" {
+		watched = append(watched, configPath)
+		app, lines, decrypted, err := parseFile(configPath)
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			add(configPath, app, lines, decrypted)
+			if configVal, ok := app.get("config").(string); ok {
+				env = configVal
+			}
+		}
+	}
+
+	for _, file := range strings.Split(env, ",") {
+		file = strings.TrimSpace(file)
+		if file == "" {
+			continue
+		}
+		// 所有扩展名都监听，文件之后以另一个扩展名出现时也能发现
+		filePath := resolveFile(configDir, file)
+		watched = append(watched, candidateFiles(configDir, file)...)
+		t, lines, decrypted, err := parseFile(filePath)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		add(filePath, t, lines, decrypted)
+	}
+
+	c.mu.Lock()
+	c.paths = watched
+	c.mu.Unlock()
+	return tree, origins, strings.Join(loaded, ","), errors.Join(errs...)
+}
+
+func (c *fileChain) watchPaths() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.paths
+}
+
+// FileSource 返回读取单个 yaml 或 JSON 文件的来源，yaml 文件中的多个文档按顺序合并
+// 用法: config.WithSource("local", config.PriorityFiles+1, config.FileSource("./config/local.yaml"))
+func FileSource(path string) Source {
+	return fileSource(path)
+}
+
+type fileSource string
+
+func (f fileSource) Load(ctx context.Context) (map[string]any, error) {
+	data, _, _, err := parseFile(string(f))
+	return data, err
+}
+
+func (f fileSource) loadLayer(ctx context.Context) (config, provenance, string, error) {
+	data, lines, decrypted, err := parseFile(string(f))
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return data, originsOf(data, fileOrigin(string(f), lines, decrypted)), string(f), nil
+}
+
+func (f fileSource) watchPaths() []string {
+	return []string{string(f)}
+}
+
+// FlagSource 返回读取命令行参数的来源，只包含显式设置过的参数
+// 参数名中的 "." 表示嵌套，例如 -server.port=9090 覆盖 server.port；参数需要在加载前解析
+//
+//	flag.Int("server.port", 8080, "listen port")
+//	flag.Parse()
+//	l := config.New(config.WithSource("flags", config.PriorityFlags, config.FlagSource(flag.CommandLine)))
+func FlagSource(fs *flag.FlagSet) Source {
+	return &flagSource{fs: fs}
+}
+
+type flagSource struct {
+	fs *flag.FlagSet
+}
+
+func (f *flagSource) Load(ctx context.Context) (map[string]any, error) {
+	data, _, _, err := f.loadLayer(ctx)
+	return data, err
+}
+
+func (f *flagSource) loadLayer(ctx context.Context) (config, provenance, string, error) {
+	tree, origins := config{}, provenance{}
+	f.fs.Visit(func(fl *flag.Flag) {
+		var v interface{} = fl.Value.String()
+		if g, ok := fl.Value.(flag.Getter); ok {
+			v = g.Get()
+		}
+		// yaml 只能从字符串解码 time.Duration
+		if d, ok := v.(time.Duration); ok {
+			v = d.String()
+		}
+		segs := strings.Split(fl.Name, ".")
+		node := map[string]interface{}(tree)
+		for _, seg := range segs[:len(segs)-1] {
+			child, ok := node[seg].(map[string]interface{})
+			if !ok {
+				child = map[string]interface{}{}
+				node[seg] = child
+			}
+			node = child
+		}
+		node[segs[len(segs)-1]] = v
+		origins[fl.Name] = []Origin{{Kind: FromFlag, Source: fl.Name, Value: v}}
+	})
+	return tree, origins, "flags", nil
+}
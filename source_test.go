@@ -0,0 +1,272 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"testing"
+	"time"
+)
+
+// fakeSource 是测试用的来源，Load 返回 data，Watch 转发 events
+type fakeSource struct {
+	data   map[string]any
+	err    error
+	events chan Event
+}
+
+func (f *fakeSource) Load(ctx context.Context) (map[string]any, error) {
+	return f.data, f.err
+}
+
+func (f *fakeSource) Watch(ctx context.Context) <-chan Event {
+	return f.events
+}
+
+// overwriteSource 是以 overwrite 模式合并的 fakeSource
+type overwriteSource struct {
+	fakeSource
+}
+
+func (o *overwriteSource) Overwrite() bool {
+	return true
+}
+
+func TestSourcePriority(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml": "server:\n  name: app\n  port: 8080\n",
+	})
+	remote := &fakeSource{data: map[string]any{"server": map[string]any{"port": 9090}}}
+	low := &fakeSource{data: map[string]any{"server": map[string]any{"name": "low", "port": 1}}}
+	l := New(
+		WithConfigPath(dir+"/app.yaml"),
+		WithSource("remote", PriorityRemote, remote),
+		WithSource("low", PriorityFiles-1, low),
+	)
+	srv := RegisterTo(l, &server{})
+	if srv.Name != "app" || srv.Port != 9090 {
+		t.Errorf("Expected name app from files and port 9090 from remote, got %+v", srv)
+	}
+
+	// UpdateConfig 优先于所有来源
+	if err := l.UpdateConfig([]byte("server:\n  port: 7070\n"), "merge"); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	if srv.Port != 7070 {
+		t.Errorf("Expected update to win, got %d", srv.Port)
+	}
+
+	got := l.Explain("server.port")
+	if len(got) != 4 || got[0].Source != "low" || got[2].Kind != FromSource || got[2].Source != "remote" || got[3].Kind != FromUpdate {
+		t.Errorf("Unexpected override chain: %v", got)
+	}
+}
+
+func TestFlagSource(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Int("server.port", 8080, "")
+	fs.String("server.name", "unset", "")
+	fs.Duration("validatedServer.timeout", time.Second, "")
+	if err := fs.Parse([]string{"-server.port=9090", "-validatedServer.timeout=3s"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := FlagSource(fs).Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	l := New(WithConfigPath(writeConfigFiles(t, map[string]string{"app.yaml": "server:\n  name: app\n"})+"/app.yaml"),
+		WithSource("flags", PriorityFlags, FlagSource(fs)))
+	srv := RegisterTo(l, &server{})
+	if srv.Port != 9090 || srv.Name != "app" {
+		t.Errorf("Expected port from flag and name from file, got %+v (flags %v)", srv, data)
+	}
+	if got := l.Explain("server.port"); len(got) != 1 || got[0].String() != "flag -server.port = 9090" {
+		t.Errorf("Unexpected origins: %v", got)
+	}
+	if _, ok := data["server"].(map[string]interface{})["name"]; ok {
+		t.Error("Flags that were not set should not be loaded")
+	}
+}
+
+func TestAddSource(t *testing.T) {
+	l := newTestLoader(t)
+	srv := RegisterTo(l, &server{})
+	changed := 0
+	OnChangeIn(l, srv, func(old, new server) { changed++ })
+
+	ctx := context.Background()
+	if err := l.AddSource(ctx, "remote", PriorityRemote, &fakeSource{data: map[string]any{"server": map[string]any{"port": 9090}}}); err != nil {
+		t.Fatalf("AddSource failed: %v", err)
+	}
+	if srv.Port != 9090 || changed != 1 {
+		t.Errorf("Expected port 9090 and one change, got %d and %d", srv.Port, changed)
+	}
+	if err := l.AddSource(ctx, "remote", PriorityRemote, &fakeSource{}); err == nil {
+		t.Error("Expected error for duplicate source name")
+	}
+	boom := errors.New("boom")
+	if err := l.AddSource(ctx, "broken", PriorityRemote, &fakeSource{err: boom}); !errors.Is(err, boom) {
+		t.Errorf("Expected load error, got %v", err)
+	}
+	if hist := l.History(); hist[len(hist)-1].Source != "source:remote" {
+		t.Errorf("Expected last revision from source:remote, got %s", hist[len(hist)-1].Source)
+	}
+}
+
+func TestWatchSources(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml": "server:\n  name: app\n  port: 8080\n",
+	})
+	remote := &fakeSource{
+		data:   map[string]any{"server": map[string]any{"port": 9090}},
+		events: make(chan Event),
+	}
+	l := New(WithConfigPath(dir+"/app.yaml"), WithSource("remote", PriorityRemote, remote))
+	srv := RegisterTo(l, &server{})
+	ch, cancel := l.Watch("server")
+	defer cancel()
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	l.WatchSources(ctx)
+
+	// 推送的内容替换来源原来的内容，被删除的 key 回落到配置文件
+	remote.events <- Event{Data: map[string]any{"server": map[string]any{"name": "remote"}}}
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for change")
+	}
+	if srv.Name != "remote" || srv.Port != 8080 {
+		t.Errorf("Expected name remote and port 8080, got %+v", srv)
+	}
+
+	// 错误被记录，配置保持不变
+	remote.events <- Event{Err: errors.New("connection lost")}
+	// 不带内容的事件重新调用 Load
+	remote.events <- Event{}
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for reload")
+	}
+	if srv.Port != 9090 || srv.Name != "app" {
+		t.Errorf("Expected reloaded source, got %+v", srv)
+	}
+	var found bool
+	for _, e := range l.Errors() {
+		found = found || e.Source == "remote"
+	}
+	if !found {
+		t.Errorf("Expected source error to be recorded, got %v", l.Errors())
+	}
+}
+
+func TestWatchSource(t *testing.T) {
+	remote := &fakeSource{events: make(chan Event)}
+	l := New(WithConfigPath(writeConfigFiles(t, map[string]string{"app.yaml": "server:\n  port: 8080\n"})+"/app.yaml"),
+		WithSource("remote", PriorityRemote, remote))
+	srv := RegisterTo(l, &server{})
+	ch, cancel := l.Watch("server")
+	defer cancel()
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	if err := l.WatchSource(ctx, "missing"); err == nil {
+		t.Error("Expected error for missing source")
+	}
+	if err := l.WatchSource(ctx, "files"); err == nil {
+		t.Error("Expected error for source without Watcher")
+	}
+	if err := l.WatchSource(ctx, "remote"); err != nil {
+		t.Fatalf("WatchSource failed: %v", err)
+	}
+	remote.events <- Event{Data: map[string]any{"server": map[string]any{"port": 9090}}}
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for change")
+	}
+	if srv.Port != 9090 {
+		t.Errorf("Expected port 9090, got %d", srv.Port)
+	}
+}
+
+func TestOverwriteSource(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml": "nacos:\n  dataId: app.yaml\nserver:\n  name: app\n  port: 8080\n",
+	})
+	data, err := Parse([]byte("{\"server\": {\"port\": 9090}}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote := &overwriteSource{fakeSource{data: data}}
+	l := New(WithConfigPath(dir+"/app.yaml"), WithSource("remote", PriorityRemote, remote))
+	srv := RegisterTo(l, &server{})
+	if srv.Port != 9090 || srv.Name != "" {
+		t.Errorf("Expected remote source to drop file config, got %+v", srv)
+	}
+	if got := l.Explain("nacos.dataId"); len(got) != 1 || got[0].Kind != FromFile {
+		t.Errorf("Expected nacos section to survive overwrite, got %v", got)
+	}
+
+	// 优先级更高的来源和更新照常合并
+	if err := l.UpdateConfig([]byte("server:\n  name: update\n"), "merge"); err != nil {
+		t.Fatal(err)
+	}
+	if srv.Port != 9090 || srv.Name != "update" {
+		t.Errorf("Expected update to merge over remote source, got %+v", srv)
+	}
+}
+
+func TestOverwriteKeepsSourceNacos(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml": "nacos:\n  ipAddr: 127.0.0.1\nserver:\n  port: 8080\n",
+	})
+	l := New(WithConfigPath(dir + "/app.yaml"))
+	srv := RegisterTo(l, &server{})
+	if err := l.UpdateConfig([]byte("server:\n  name: remote\n"), "overwrite"); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	if srv.Port != 0 || srv.Name != "remote" {
+		t.Errorf("Expected overwrite to drop file config, got %+v", srv)
+	}
+	// 重新加载来源后 overwrite 依然生效，nacos 配置保留
+	if err := l.LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	l.mu.RLock()
+	nacos := l.data.get("nacos")
+	port := l.data.get("server").(map[string]interface{})["port"]
+	l.mu.RUnlock()
+	if nacos == nil || port != nil {
+		t.Errorf("Expected only nacos to survive from files, got nacos=%v port=%v", nacos, port)
+	}
+}
+
+func TestKeepOnOverwrite(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml": "etcd:\n  prefix: /app/\nvault:\n  addr: a\nlocal:\n  x: 1\nserver:\n  port: 8080\n",
+	})
+	l := New(WithConfigPath(dir+"/app.yaml"), WithKeepOnOverwrite("vault", "vault"))
+	if err := l.UpdateConfig([]byte("server:\n  port: 9090\n"), "overwrite"); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	l.mu.RLock()
+	if l.data.get("etcd") == nil || l.data.get("vault") == nil || l.data.get("local") != nil {
+		t.Errorf("Expected etcd and vault to survive overwrite, got %v", l.data)
+	}
+	l.mu.RUnlock()
+
+	// 保留的 key 只属于该 Loader
+	other := New(WithConfigPath(dir + "/app.yaml"))
+	if err := other.UpdateConfig([]byte("server:\n  port: 9090\n"), "overwrite"); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	other.mu.RLock()
+	defer other.mu.RUnlock()
+	if other.data.get("vault") != nil {
+		t.Errorf("Expected vault to be dropped by other loader, got %v", other.data)
+	}
+}
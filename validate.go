@@ -0,0 +1,211 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Validator 是 section 类型可以实现的可选接口，在 validate 标签校验通过后调用
+// 对 RegisterMap 注册的 section，每个值分别校验
+type Validator interface {
+	Validate() error
+}
+
+// ValidationError 描述一个字段未通过 validate 标签中的某条规则
+type ValidationError struct {
+	Field string // 字段路径，使用 yaml 名称，例如 default.uri
+	Rule  string // 未通过的规则，例如 min=1
+	Msg   string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Field == "" {
+		return e.Msg
+	}
+	return e.Field + ": " + e.Msg
+}
+
+// validateValue 按 validate 标签校验 v 及其嵌套的结构体，并调用实现了 Validator 的值
+// 支持的规则（逗号分隔）：
+//
+//	required      值不能为零值，切片和 map 不能为空
+//	min=N, max=N  数字比较大小，字符串比较字符数，切片和 map 比较长度，time.Duration 可以写 min=1s
+//	oneof=a b c   值必须是列出的之一，零值跳过
+//	url           必须是带 scheme 和 host 的 URL，零值跳过
+func validateValue(v interface{}) error {
+	var errs []error
+	validateWalk(reflect.ValueOf(v), "", &errs)
+	return errors.Join(errs...)
+}
+
+func validateWalk(v reflect.Value, path string, errs *[]error) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name := yamlFieldName(f)
+			if name == "" {
+				continue
+			}
+			fieldPath := joinPath(path, name)
+			if rules := f.Tag.Get("validate"); rules != "" {
+				secret := f.Tag.Get("secret") == "true" || isSecretType(f.Type)
+				for _, rule := range strings.Split(rules, ",") {
+					if err := checkRule(v.Field(i), strings.TrimSpace(rule), secret); err != nil {
+						*errs = append(*errs, &ValidationError{Field: fieldPath, Rule: rule, Msg: err.Error()})
+					}
+				}
+			}
+			validateWalk(v.Field(i), fieldPath, errs)
+		}
+		if v.CanAddr() {
+			if val, ok := v.Addr().Interface().(Validator); ok {
+				if err := val.Validate(); err != nil {
+					*errs = append(*errs, prefixError(path, err))
+				}
+			}
+		} else if val, ok := v.Interface().(Validator); ok {
+			if err := val.Validate(); err != nil {
+				*errs = append(*errs, prefixError(path, err))
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			validateWalk(v.Index(i), joinPath(path, strconv.Itoa(i)), errs)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			validateWalk(iter.Value(), joinPath(path, fmt.Sprint(iter.Key().Interface())), errs)
+		}
+	}
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func prefixError(path string, err error) error {
+	if path == "" {
+		return err
+	}
+	return fmt.Errorf("%s: %w", path, err)
+}
+
+// checkRule 检查单条规则，返回不带字段路径的错误
+// secret 表示字段是 Secret 或带 secret:"true" 标签，错误信息中不包含字段的值
+func checkRule(v reflect.Value, rule string, secret bool) error {
+	name, arg, _ := strings.Cut(rule, "=")
+	switch name {
+	case "":
+		return nil
+	case "required":
+		if isEmptyValue(v) {
+			return errors.New("is required")
+		}
+	case "min", "max":
+		return checkBound(v, name, arg)
+	case "oneof":
+		if v.IsZero() {
+			return nil
+		}
+		// Secret 的 String 方法返回脱敏后的值，字符串类型直接比较原始值
+		s := fmt.Sprint(v.Interface())
+		if v.Kind() == reflect.String {
+			s = v.String()
+		}
+		for _, opt := range strings.Fields(arg) {
+			if s == opt {
+				return nil
+			}
+		}
+		if secret {
+			return fmt.Errorf("must be one of [%s]", arg)
+		}
+		return fmt.Errorf("must be one of [%s], got %q", arg, s)
+	case "url":
+		if v.Kind() != reflect.String || v.Len() == 0 {
+			return nil
+		}
+		u, err := url.Parse(v.String())
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			if secret {
+				return errors.New("must be a valid URL")
+			}
+			return fmt.Errorf("must be a valid URL, got %q", v.String())
+		}
+	default:
+		return fmt.Errorf("unknown validation rule %q", rule)
+	}
+	return nil
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// checkBound 检查 min / max 规则
+func checkBound(v reflect.Value, name, arg string) error {
+	var (
+		actual, bound float64
+		err           error
+		what          = ""
+	)
+	switch {
+	case v.Type() == durationType:
+		var d time.Duration
+		if d, err = time.ParseDuration(arg); err != nil {
+			var n int64
+			n, err = strconv.ParseInt(arg, 10, 64)
+			d = time.Duration(n)
+		}
+		actual, bound = float64(v.Int()), float64(d)
+	case v.Kind() == reflect.String:
+		actual, what = float64(utf8.RuneCountInString(v.String())), " characters"
+		bound, err = strconv.ParseFloat(arg, 64)
+	case v.Kind() == reflect.Slice || v.Kind() == reflect.Map || v.Kind() == reflect.Array:
+		actual, what = float64(v.Len()), " items"
+		bound, err = strconv.ParseFloat(arg, 64)
+	case v.CanInt():
+		actual = float64(v.Int())
+		bound, err = strconv.ParseFloat(arg, 64)
+	case v.CanUint():
+		actual = float64(v.Uint())
+		bound, err = strconv.ParseFloat(arg, 64)
+	case v.CanFloat():
+		actual = v.Float()
+		bound, err = strconv.ParseFloat(arg, 64)
+	default:
+		return fmt.Errorf("rule %s is not supported for %s", name, v.Type())
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s argument %q", name, arg)
+	}
+	if name == "min" && actual < bound {
+		return fmt.Errorf("must be at least %s%s", arg, what)
+	}
+	if name == "max" && actual > bound {
+		return fmt.Errorf("must be at most %s%s", arg, what)
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type validatedServer struct {
+	Port    int           `yaml:"port" validate:"required,min=1,max=65535"`
+	Url     string        `yaml:"url" validate:"url"`
+	Mode    string        `yaml:"mode" validate:"oneof=merge overwrite"`
+	Name    string        `yaml:"name" validate:"min=2,max=16"`
+	Origins []string      `yaml:"origins" validate:"max=2"`
+	Timeout time.Duration `yaml:"timeout" validate:"min=1s"`
+}
+
+type validatedMongo struct {
+	URI string `yaml:"uri" validate:"required,url"`
+}
+
+type validatedGateway struct {
+	DevMode    bool   `yaml:"devMode"`
+	ConfigPath string `yaml:"configPath"`
+}
+
+func (g *validatedGateway) Validate() error {
+	if !g.DevMode && g.ConfigPath == "" {
+		return errors.New("configPath is required outside dev mode")
+	}
+	return nil
+}
+
+func TestValidateValue(t *testing.T) {
+	ok := &validatedServer{Port: 8080, Url: "http://localhost", Mode: "merge", Name: "app", Timeout: time.Second}
+	if err := validateValue(ok); err != nil {
+		t.Errorf("Expected valid server, got %v", err)
+	}
+
+	bad := &validatedServer{Port: 70000, Url: "localhost", Mode: "replace", Name: "a", Origins: []string{"a", "b", "c"}}
+	err := validateValue(bad)
+	if err == nil {
+		t.Fatal("Expected validation errors")
+	}
+	for _, want := range []string{
+		"port: must be at most 65535",
+		"url: must be a valid URL",
+		"mode: must be one of [merge overwrite]",
+		"name: must be at least 2 characters",
+		"origins: must be at most 2 items",
+		"timeout: must be at least 1s",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error containing %q, got:\n%v", want, err)
+		}
+	}
+	var ve *ValidationError
+	if !errors.As(err, &ve) || ve.Rule == "" {
+		t.Errorf("Expected *ValidationError, got %#v", err)
+	}
+
+	if err := validateValue(&validatedServer{}); err == nil || !strings.Contains(err.Error(), "port: is required") {
+		t.Errorf("Expected required error, got %v", err)
+	}
+	if err := validateValue(map[string]*validatedMongo{"default": {}}); err == nil || !strings.Contains(err.Error(), "default.uri: is required") {
+		t.Errorf("Expected map entry error, got %v", err)
+	}
+	if err := validateValue(&validatedGateway{}); err == nil || !strings.Contains(err.Error(), "configPath is required") {
+		t.Errorf("Expected Validator error, got %v", err)
+	}
+	var unknown struct {
+		A string `validate:"nope"`
+	}
+	if err := validateValue(&unknown); err == nil || !strings.Contains(err.Error(), "unknown validation rule") {
+		t.Errorf("Expected unknown rule error, got %v", err)
+	}
+}
+
+// validatedSecrets 的 Secret 字段和带 secret 标签的字段同样参与校验
+type validatedSecrets struct {
+	DSN   Secret `yaml:"dsn" validate:"url"`
+	Token string `yaml:"token" secret:"true" validate:"url"`
+	Level Secret `yaml:"level" validate:"oneof=low high"`
+}
+
+func TestValidateSecretURL(t *testing.T) {
+	err := validateValue(&validatedSecrets{DSN: "plaintext-dsn", Token: "plaintext-token"})
+	if err == nil {
+		t.Fatal("Expected validation errors")
+	}
+	// 错误信息只有字段路径，不包含明文
+	if msg := err.Error(); strings.Contains(msg, "plaintext") ||
+		!strings.Contains(msg, "dsn: must be a valid URL") || !strings.Contains(msg, "token: must be a valid URL") {
+		t.Errorf("Unexpected error: %v", msg)
+	}
+}
+
+func TestValidateSecretOneof(t *testing.T) {
+	// oneof 比较 Secret 的原始值，而不是脱敏后的 ******
+	if err := validateValue(&validatedSecrets{Level: "high"}); err != nil {
+		t.Errorf("Expected valid secret, got %v", err)
+	}
+	err := validateValue(&validatedSecrets{Level: "plaintext"})
+	if err == nil || strings.Contains(err.Error(), "plaintext") || !strings.Contains(err.Error(), "level: must be one of [low high]") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestValidateOnLoad(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml": "config: dev\n",
+		"dev.yaml": "validatedServer:\n  port: -1\n",
+	})
+	l := New(WithConfigPath(filepath.Join(dir, "app.yaml")))
+	RegisterTo(l, &validatedServer{})
+
+	errs := l.Errors()
+	if len(errs) != 1 || errs[0].Section != "validatedServer" || !strings.Contains(errs[0].Error(), "must be at least 1") {
+		t.Fatalf("Expected validation error on load, got %v", errs)
+	}
+}
+
+func TestValidateRejectsUpdate(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml": "config: dev\n",
+		"dev.yaml": `
+validatedServer:
+  port: 8080
+  name: app
+  timeout: 5s
+mongo:
+  default:
+    uri: mongodb://localhost:27017
+validatedGateway:
+  devMode: true
+`,
+	})
+	l := New(WithConfigPath(filepath.Join(dir, "app.yaml")))
+	srv := RegisterTo(l, &validatedServer{})
+	mongoMap := RegisterMapTo[*validatedMongo](l, "mongo")
+	gw := RegisterTo(l, &validatedGateway{})
+	if err := l.Err(); err != nil {
+		t.Fatalf("Unexpected load error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		data string
+		mode string
+		want string
+	}{
+		{"tagRule", "validatedServer:\n  port: -1\n", "merge", "must be at least 1"},
+		{"mapEntry", "mongo:\n  default:\n    uri: \"\"\n", "merge", "default.uri: is required"},
+		{"validator", "validatedGateway:\n  devMode: false\n", "merge", "configPath is required"},
+		{"overwrite", "validatedServer:\n  port: 9090\n", "overwrite", "timeout: must be at least 1s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := l.UpdateConfig([]byte(tt.data), tt.mode)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Expected error containing %q, got %v", tt.want, err)
+			}
+			if srv.Port != 8080 || mongoMap.Default() == nil || mongoMap.Default().URI == "" || !gw.DevMode {
+				t.Errorf("Expected sections to be untouched, got %+v %+v %+v", srv, mongoMap.Default(), gw)
+			}
+		})
+	}
+
+	// 被拒绝的更新不会留在配置树中
+	if err := l.UpdateConfig([]byte("validatedServer:\n  port: 9090\n"), "merge"); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	if srv.Port != 9090 || mongoMap.Default().URI != "mongodb://localhost:27017" || !gw.DevMode {
+		t.Errorf("Unexpected state after valid update: %+v %+v %+v", srv, mongoMap.Default(), gw)
+	}
+}
+
+func TestValidateRejectsReload(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml": "config: dev\n",
+		"dev.yaml": "validatedServer:\n  port: 8080\n  name: app\n  timeout: 5s\n",
+	})
+	l := New(WithConfigPath(filepath.Join(dir, "app.yaml")))
+	srv := RegisterTo(l, &validatedServer{})
+
+	// 重新加载与 UpdateConfig 一样校验，失败时保持当前配置
+	os.WriteFile(filepath.Join(dir, "dev.yaml"), []byte("validatedServer:\n  port: -5\n  name: app\n  timeout: 5s\n"), 0o644)
+	if err := l.LoadConfig(); err == nil || !strings.Contains(err.Error(), "must be at least 1") {
+		t.Fatalf("Expected validation error, got %v", err)
+	}
+	if srv.Port != 8080 || len(l.Errors()) != 1 {
+		t.Errorf("Expected server untouched and error recorded, got %+v %v", srv, l.Errors())
+	}
+	if _, err := l.Apply([]byte("validatedServer:\n  name: next\n"), "merge"); err != nil {
+		t.Fatalf("Expected later updates to apply, got %v", err)
+	}
+
+	ch, cancel := l.Watch("validatedServer")
+	defer cancel()
+	os.WriteFile(filepath.Join(dir, "dev.yaml"), []byte("validatedServer:\n  port: 9090\n  name: app\n  timeout: 5s\n"), 0o644)
+	if err := l.LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if srv.Port != 9090 || srv.Name != "next" || l.Err() != nil {
+		t.Errorf("Expected reload to apply, got %+v %v", srv, l.Err())
+	}
+	waitChange(t, ch)
+	if hist := l.History(); hist[len(hist)-1].Changes[0].Path != "validatedServer.port" {
+		t.Errorf("Unexpected history %v", hist)
+	}
+}
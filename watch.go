@@ -0,0 +1,170 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// subscription 是对某个 section 变化的订阅，回调收到的是变化前后的原始配置子树
+type subscription struct {
+	section string
+	fn      func(old, new interface{})
+}
+
+// sectionChange 描述一次更新中内容发生变化的 section
+type sectionChange struct {
+	name string
+	old  interface{}
+	new  interface{}
+}
+
+// diffSections 比较更新前后 section 实际使用的配置树，返回内容发生变化的顶层 section
+// 两棵树计算后都不再修改，返回的子树可以在释放锁之后安全使用
+func diffSections(prev, cur map[string]interface{}) []sectionChange {
+	var changes []sectionChange
+	seen := map[string]bool{}
+	for _, c := range diffTrees(prev, cur) {
+		name, _, _ := strings.Cut(c.Path, ".")
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		changes = append(changes, sectionChange{name: name, old: prev[name], new: cur[name]})
+	}
+	return changes
+}
+
+// subscribe 注册一个订阅，返回的函数用于取消订阅，可重复调用
+func (l *Loader) subscribe(section string, fn func(old, new interface{})) func() {
+	sub := &subscription{section: section, fn: fn}
+	l.subMu.Lock()
+	l.subs = append(l.subs, sub)
+	l.subMu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.subMu.Lock()
+			defer l.subMu.Unlock()
+			for i, s := range l.subs {
+				if s == sub {
+					l.subs = append(l.subs[:i], l.subs[i+1:]...)
+					break
+				}
+			}
+		})
+	}
+}
+
+// enqueue 将一次提交的变化加入通知队列，调用方必须持有写锁，保证队列的顺序与提交顺序一致
+func (l *Loader) enqueue(changes []sectionChange) {
+	if len(changes) == 0 {
+		return
+	}
+	l.notifyMu.Lock()
+	l.queue = append(l.queue, changes)
+	l.notifyMu.Unlock()
+}
+
+// notify 按提交顺序通知订阅者，必须在释放写锁之后调用，回调中可以安全地读取配置、更新配置或取消订阅
+// 同一时刻只有一个 goroutine 投递通知，其他 goroutine 提交的变化由它依次投递，
+// 因此并发的更新不会乱序，回调中的更新也会在当前回调返回之后才通知
+func (l *Loader) notify() {
+	l.notifyMu.Lock()
+	if l.notifying {
+		l.notifyMu.Unlock()
+		return
+	}
+	l.notifying = true
+	for len(l.queue) > 0 {
+		changes := l.queue[0]
+		l.queue = l.queue[1:]
+		l.notifyMu.Unlock()
+
+		l.subMu.Lock()
+		subs := append([]*subscription(nil), l.subs...)
+		l.subMu.Unlock()
+		for _, c := range changes {
+			for _, s := range subs {
+				if s.section == c.name {
+					s.fn(c.old, c.new)
+				}
+			}
+		}
+
+		l.notifyMu.Lock()
+	}
+	l.notifying = false
+	l.notifyMu.Unlock()
+}
+
+// sectionName 返回 target 对应的已注册 section 名称
+// target 可以是 Register 的指针、RegisterMap 返回的 map 或 Load 的 section
+func (l *Loader) sectionName(target interface{}) string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for _, section := range l.registry {
+		if o, ok := section.(interface{ owns(interface{}) bool }); ok {
+			if o.owns(target) {
+				return section.SectionName()
+			}
+		} else if section == target {
+			// 自定义的 Reloader 直接保存在注册表中
+			return section.SectionName()
+		}
+	}
+	panic(fmt.Sprintf("config: %T is not a registered section", target))
+}
+
+// OnChange 订阅默认 Loader 中某个 section 的变化，返回取消订阅的函数
+// section 必须是 Register 返回的指针或传给 Load 的 section
+// 只有 UpdateConfig 之后内容确实发生变化时才会回调，old 和 new 都是独立解码出的新值
+// 用法: cancel := config.OnChange(sections.Log, func(old, new log) { ... })
+func OnChange[T any](section *T, fn func(old, new T)) func() {
+	return OnChangeIn(std, section, fn)
+}
+
+// OnChangeIn 与 OnChange 相同，但订阅指定的 Loader
+func OnChangeIn[T any](l *Loader, section *T, fn func(old, new T)) func() {
+	return l.subscribe(l.sectionName(section), func(oldData, newData interface{}) {
+		// 解码错误已经在 UpdateConfig 中返回，这里只传递能解码的部分
+		var o, n T
+		_ = decodeSection(oldData, &o)
+		_ = decodeSection(newData, &n)
+		fn(o, n)
+	})
+}
+
+// OnChangeMap 订阅默认 Loader 中 RegisterMap 注册的 section 的变化，返回取消订阅的函数
+func OnChangeMap[V any](section SectionMap[V], fn func(old, new SectionMap[V])) func() {
+	return OnChangeMapIn(std, section, fn)
+}
+
+// OnChangeMapIn 与 OnChangeMap 相同，但订阅指定的 Loader
+func OnChangeMapIn[V any](l *Loader, section SectionMap[V], fn func(old, new SectionMap[V])) func() {
+	return l.subscribe(l.sectionName(section), func(oldData, newData interface{}) {
+		o, _ := decodeSectionMap[V](oldData)
+		n, _ := decodeSectionMap[V](newData)
+		fn(o, n)
+	})
+}
+
+// Watch 按名称订阅默认 Loader 中 section 的变化
+// section 每次变化都会向返回的 channel 发送通知，未及时消费的通知会被合并
+// 返回的函数用于取消订阅，取消后 channel 不会再收到通知，但也不会被关闭
+func Watch(name string) (<-chan struct{}, func()) {
+	return std.Watch(name)
+}
+
+// Watch 按名称订阅该 Loader 中 section 的变化，语义同包级函数 Watch
+func (l *Loader) Watch(name string) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	cancel := l.subscribe(name, func(_, _ interface{}) {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	})
+	return ch, cancel
+}
@@ -0,0 +1,238 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestLoader(t *testing.T) *Loader {
+	t.Helper()
+	dir := writeConfigFiles(t, map[string]string{
+		"app.yaml": "config: dev\n",
+		"dev.yaml": `
+server:
+  name: app
+  port: 8080
+logConfig:
+  level: INFO
+mongo:
+  default:
+    uri: mongodb://localhost:27017
+`,
+	})
+	return New(WithConfigPath(filepath.Join(dir, "app.yaml")))
+}
+
+func TestOnChange(t *testing.T) {
+	l := newTestLoader(t)
+	srv := RegisterTo(l, &server{})
+	logCfg := RegisterTo(l, &logConfig{})
+
+	var calls []server
+	var olds []server
+	cancel := OnChangeIn(l, srv, func(old, new server) {
+		olds = append(olds, old)
+		calls = append(calls, new)
+	})
+	logCalls := 0
+	OnChangeIn(l, logCfg, func(old, new logConfig) { logCalls++ })
+
+	if err := l.UpdateConfig([]byte("server:\n  port: 9090\n"), "merge"); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	if len(calls) != 1 {
+		t.Fatalf("Expected 1 server change, got %d", len(calls))
+	}
+	if olds[0].Port != 8080 || calls[0].Port != 9090 || calls[0].Name != "app" {
+		t.Errorf("Unexpected change: old=%+v new=%+v", olds[0], calls[0])
+	}
+	if logCalls != 0 {
+		t.Errorf("Expected no logConfig change, got %d", logCalls)
+	}
+
+	// 内容未变化时不回调
+	if err := l.UpdateConfig([]byte("server:\n  port: 9090\n"), "merge"); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	if len(calls) != 1 {
+		t.Errorf("Expected no callback for identical content, got %d calls", len(calls))
+	}
+
+	cancel()
+	cancel()
+	if err := l.UpdateConfig([]byte("server:\n  port: 7070\n"), "merge"); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	if len(calls) != 1 {
+		t.Errorf("Expected no callback after cancel, got %d calls", len(calls))
+	}
+}
+
+func TestOnChangeCallbackWithoutLock(t *testing.T) {
+	l := newTestLoader(t)
+	srv := RegisterTo(l, &server{})
+
+	done := false
+	OnChangeIn(l, srv, func(old, new server) {
+		// 回调在释放写锁之后执行，可以再次读取或更新配置
+		l.mu.RLock()
+		l.mu.RUnlock()
+		done = true
+	})
+	if err := l.UpdateConfig([]byte("server:\n  port: 9090\n"), "merge"); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	if !done {
+		t.Error("Expected callback to run")
+	}
+}
+
+func TestOnChangeOrder(t *testing.T) {
+	l := newTestLoader(t)
+	srv := RegisterTo(l, &server{})
+
+	var (
+		mu    sync.Mutex
+		ports []int
+	)
+	OnChangeIn(l, srv, func(old, new server) {
+		time.Sleep(100 * time.Microsecond)
+		mu.Lock()
+		defer mu.Unlock()
+		// 每次通知的旧值都是上一次通知的新值
+		if len(ports) > 0 && old.Port != ports[len(ports)-1] {
+			t.Errorf("Out of order: old %d after new %d", old.Port, ports[len(ports)-1])
+		}
+		ports = append(ports, new.Port)
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(port int) {
+			defer wg.Done()
+			if err := l.UpdateConfig([]byte(fmt.Sprintf("server:\n  port: %d\n", port)), "merge"); err != nil {
+				t.Error(err)
+			}
+		}(10000 + i)
+	}
+	wg.Wait()
+
+	// 通知顺序与历史中的提交顺序一致
+	var committed []int
+	for _, rev := range l.History() {
+		for _, c := range rev.Changes {
+			if c.Path == "server.port" {
+				committed = append(committed, c.New.(int))
+			}
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(ports) != 50 || !slices.Equal(ports[len(ports)-len(committed):], committed) {
+		t.Errorf("Expected notifications %v to follow commits %v", ports, committed)
+	}
+}
+
+func TestOnChangeNestedUpdate(t *testing.T) {
+	l := newTestLoader(t)
+	srv := RegisterTo(l, &server{})
+
+	var ports []int
+	OnChangeIn(l, srv, func(old, new server) {
+		ports = append(ports, new.Port)
+		// 回调中的更新在当前回调返回之后通知
+		if new.Port == 9090 {
+			if err := l.UpdateConfig([]byte("server:\n  port: 9091\n"), "merge"); err != nil {
+				t.Error(err)
+			}
+			if len(ports) != 1 {
+				t.Errorf("Expected nested notification to be deferred, got %v", ports)
+			}
+		}
+	})
+	if err := l.UpdateConfig([]byte("server:\n  port: 9090\n"), "merge"); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(ports, []int{9090, 9091}) || srv.Port != 9091 {
+		t.Errorf("Unexpected notifications %v, port %d", ports, srv.Port)
+	}
+}
+
+func TestOnChangeMap(t *testing.T) {
+	l := newTestLoader(t)
+	mongoMap := RegisterMapTo[*mongo](l, "mongo")
+
+	var got SectionMap[*mongo]
+	OnChangeMapIn(l, mongoMap, func(old, new SectionMap[*mongo]) {
+		if old.Default() == nil || old.Default().URI != "mongodb://localhost:27017" {
+			t.Errorf("Unexpected old value: %+v", old.Default())
+		}
+		got = new
+	})
+
+	if err := l.UpdateConfig([]byte("mongo:\n  second:\n    uri: mongodb://second\n"), "merge"); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	if got == nil || got["second"] == nil || got.Default() == nil {
+		t.Fatalf("Expected change with default and second, got %v", got)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	l := newTestLoader(t)
+	RegisterTo(l, &server{})
+
+	ch, cancel := l.Watch("server")
+	defer cancel()
+	other, cancelOther := l.Watch("logConfig")
+	defer cancelOther()
+
+	if err := l.UpdateConfig([]byte("server:\n  port: 9090\n"), "merge"); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	if err := l.UpdateConfig([]byte("server:\n  port: 9091\n"), "merge"); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+
+	select {
+	case <-ch:
+	default:
+		t.Fatal("Expected server notification")
+	}
+	// 未消费的通知会被合并
+	select {
+	case <-ch:
+		t.Error("Expected notifications to be coalesced")
+	default:
+	}
+	select {
+	case <-other:
+		t.Error("Expected no logConfig notification")
+	default:
+	}
+
+	// overwrite 模式删除 section 也算变化
+	if err := l.UpdateConfig([]byte("server:\n  port: 9091\n"), "overwrite"); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	select {
+	case <-other:
+	default:
+		t.Error("Expected logConfig notification after removal")
+	}
+}
+
+func TestOnChangeUnregistered(t *testing.T) {
+	l := newTestLoader(t)
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for unregistered section")
+		}
+	}()
+	OnChangeIn(l, &server{}, func(old, new server) {})
+}